@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sanurb/ghpm/internal/config"
+	"github.com/sanurb/ghpm/internal/ghops"
+	"github.com/sanurb/ghpm/internal/manifest"
+	"github.com/spf13/cobra"
+)
+
+var applyOpts struct {
+	file string
+	root string
+}
+
+// applyCmd converges the local tree to a workspace manifest.
+var applyCmd = &cobra.Command{
+	Use:   "apply -f workspace.yaml",
+	Short: "Clone and configure the repositories listed in a workspace manifest",
+	Long: `Make the local tree match a workspace manifest: clone the repositories it
+lists that are missing, run their post-clone hooks, check out the branches it
+names and add the remotes it lists. Existing checkouts are handled according
+to on_conflict (skip by default) and never switched to another branch while
+they have uncommitted changes. Nothing is ever deleted, so on_conflict
+reclone is rejected.
+
+The workspace root is --root, else the manifest's root, else clone_root. The
+manifest's layout applies unless --layout is given. Use --dry-run to see what
+would be done. See "ghpm export" to write a manifest from an existing tree.`,
+	Example: `  ghpm apply -f workspace.yaml --dry-run
+  ghpm apply -f workspace.yaml --root ~/work`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := manifest.Load(applyOpts.file)
+		if err != nil {
+			return err
+		}
+		root := applyOpts.root
+		if root == "" {
+			root = m.RootDir()
+		}
+		if root == "" {
+			root = config.AppConfig.Root()
+		}
+		layoutName := config.AppConfig.Layout
+		if m.Layout != "" && !cmd.Flags().Changed("layout") {
+			layoutName = m.Layout
+		}
+		layout, err := ghops.ParseLayout(layoutName)
+		if err != nil {
+			return err
+		}
+		policy, err := ghops.ParseConflictPolicy(config.AppConfig.OnConflict)
+		if err != nil {
+			return err
+		}
+		if policy == ghops.ConflictReclone {
+			return fmt.Errorf("apply never deletes checkouts: on_conflict %s is not supported (use skip, pull, fetch or fail)", policy)
+		}
+
+		repos, warnings, err := ghops.ResolveManifest(m, layout, config.AppConfig.RepoLimit)
+		if err != nil {
+			return err
+		}
+		for _, w := range warnings {
+			fmt.Fprintln(os.Stderr, "warning:", w)
+		}
+		if outputFormat.Human() {
+			fmt.Printf("Applying %d repositories...\n", len(repos))
+		}
+		ctx, plan := commandContext()
+		results := ghops.ApplyWorkspace(ctx, repos, ghops.ApplyOptions{
+			Root:       root,
+			Jobs:       config.AppConfig.Jobs,
+			OnConflict: policy,
+			Hooks:      config.AppConfig.Hooks,
+			OnResult:   streamTo(printApplyResult),
+		})
+
+		failed := 0
+		for _, r := range results {
+			if r.Err != nil {
+				failed++
+			}
+		}
+		printPlan(plan)
+		if outputFormat.Human() {
+			fmt.Printf("\n%d repositories: %s.\n", len(results), ghops.Summary(results))
+		} else if err := writeResults(results); err != nil {
+			return err
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d repositories failed or conflicted", failed, len(results))
+		}
+		return nil
+	},
+}
+
+func init() {
+	f := applyCmd.Flags()
+	f.StringVarP(&applyOpts.file, "file", "f", "", "workspace manifest to apply (YAML or JSON; - for stdin)")
+	f.StringVar(&applyOpts.root, "root", "", "directory the workspace lives in (default: the manifest's root, or clone_root)")
+	applyCmd.MarkFlagRequired("file")
+
+	rootCmd.AddCommand(applyCmd)
+}
+
+// printApplyResult prints one line per finished repository, followed by
+// what was changed or why it needs attention.
+func printApplyResult(r ghops.Result) {
+	printCloneResult(r)
+	if r.Output != "" && r.Err == nil && r.Status != ghops.StatusSkipped {
+		fmt.Print(indent(r.Output))
+	}
+}
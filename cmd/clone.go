@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sanurb/ghpm/internal/config"
+	"github.com/sanurb/ghpm/internal/ghops"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var cloneOpts struct {
+	self    bool
+	user    string
+	org     string
+	dest    string
+	include []string
+	exclude []string
+
+	noForks     bool
+	noArchived  bool
+	languages   []string
+	topics      []string
+	pushedSince string
+}
+
+// cloneCmd clones repositories without the interactive TUI.
+var cloneCmd = &cobra.Command{
+	Use:   "clone",
+	Short: "Clone your own, a user's or an org's repositories",
+	Example: `  ghpm clone --self --dest ~/src
+  ghpm clone --org my-org --match 'svc-*' --exclude '*-legacy'
+  ghpm clone --user octocat -j 8
+  ghpm clone --org my-org --no-forks --no-archived --language go --pushed-since 90d`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		listing, err := listReposForClone()
+		if err != nil {
+			return err
+		}
+		if w := listing.Warning(); w != "" {
+			fmt.Fprintln(os.Stderr, "warning:", w)
+		}
+		filter, err := cloneFilter()
+		if err != nil {
+			return err
+		}
+		repos, err := filter.Apply(listing.Repos)
+		if err != nil {
+			return err
+		}
+		if len(repos) == 0 && outputFormat.Human() {
+			fmt.Println("No repositories matched.")
+			return nil
+		}
+
+		dest := cloneOpts.dest
+		if dest == "" {
+			dest = config.AppConfig.Root()
+		}
+		policy, err := ghops.ParseConflictPolicy(config.AppConfig.OnConflict)
+		if err != nil {
+			return err
+		}
+		layout, err := ghops.ParseLayout(config.AppConfig.Layout)
+		if err != nil {
+			return err
+		}
+		if outputFormat.Human() {
+			fmt.Printf("Cloning %d repositories...\n", len(repos))
+		}
+		ctx, plan := commandContext()
+		results := ghops.CloneRepos(ctx, repos, ghops.CloneOptions{
+			Dest:       dest,
+			Layout:     layout,
+			Jobs:       config.AppConfig.Jobs,
+			OnConflict: policy,
+			Hooks:      config.AppConfig.Hooks,
+			OnResult:   streamTo(printCloneResult),
+		})
+
+		failed := 0
+		for _, r := range results {
+			if r.Err != nil {
+				failed++
+			}
+		}
+		printPlan(plan)
+		if outputFormat.Human() {
+			fmt.Printf("\n%d repositories: %s.\n", len(results), ghops.Summary(results))
+		} else if err := writeResults(results); err != nil {
+			return err
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d repositories failed or conflicted", failed, len(results))
+		}
+		return nil
+	},
+}
+
+func init() {
+	f := cloneCmd.Flags()
+	f.BoolVar(&cloneOpts.self, "self", false, "clone the authenticated user's repositories")
+	f.StringVar(&cloneOpts.user, "user", "", "clone the public repositories of `name`")
+	f.StringVar(&cloneOpts.org, "org", "", "clone the repositories of organization `login`")
+	f.StringVar(&cloneOpts.dest, "dest", "", "directory to clone into (default: clone_root, or the current directory)")
+	f.StringSliceVar(&cloneOpts.include, "match", nil, "only clone repos whose name matches `glob` (repeatable)")
+	f.StringSliceVar(&cloneOpts.exclude, "exclude", nil, "skip repos whose name matches `glob` (repeatable)")
+	f.BoolVar(&cloneOpts.noForks, "no-forks", false, "skip forks")
+	f.BoolVar(&cloneOpts.noArchived, "no-archived", false, "skip archived repositories")
+	f.StringSliceVar(&cloneOpts.languages, "language", nil, "only clone repos whose primary language is `lang` (repeatable)")
+	f.StringSliceVar(&cloneOpts.topics, "topic", nil, "only clone repos tagged with `topic` (repeatable, any matches)")
+	f.StringVar(&cloneOpts.pushedSince, "pushed-since", "", "only clone repos pushed within `age` (e.g. 90d, 2w) or since a date")
+	f.String("on-conflict", "skip", "when a destination exists: skip, pull, fetch, reclone or fail")
+	viper.BindPFlag("on_conflict", f.Lookup("on-conflict"))
+	cloneCmd.MarkFlagsOneRequired("self", "user", "org")
+	cloneCmd.MarkFlagsMutuallyExclusive("self", "user", "org")
+
+	rootCmd.AddCommand(cloneCmd)
+}
+
+func listReposForClone() (ghops.Listing, error) {
+	limit := config.AppConfig.RepoLimit
+	switch {
+	case cloneOpts.self:
+		return ghops.ListSelfRepos(limit)
+	case cloneOpts.user != "":
+		return ghops.ListPublicRepos(cloneOpts.user, limit)
+	default:
+		return ghops.ListOrgRepos(cloneOpts.org, limit)
+	}
+}
+
+func cloneFilter() (ghops.RepoFilter, error) {
+	since, err := ghops.ParseSince(cloneOpts.pushedSince, time.Now())
+	if err != nil {
+		return ghops.RepoFilter{}, err
+	}
+	return ghops.RepoFilter{
+		Include:     cloneOpts.include,
+		Exclude:     cloneOpts.exclude,
+		NoForks:     cloneOpts.noForks,
+		NoArchived:  cloneOpts.noArchived,
+		Languages:   cloneOpts.languages,
+		Topics:      cloneOpts.topics,
+		PushedSince: since,
+	}, nil
+}
+
+func printCloneResult(r ghops.Result) {
+	if r.Err != nil {
+		fmt.Printf("✗ %s [%s]: %v\n", r.Name, r.Status, r.Err)
+		if r.Status == ghops.StatusHookFailed {
+			fmt.Print(indent(r.Output))
+		}
+		return
+	}
+	fmt.Printf("✓ %s [%s] (%s)\n", r.Name, r.Status, r.Duration.Round(100*time.Millisecond))
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/sanurb/ghpm/internal/config"
+	"github.com/sanurb/ghpm/internal/ghops"
+	"github.com/sanurb/ghpm/internal/github"
+	"github.com/spf13/cobra"
+)
+
+// configCmd groups the subcommands that inspect and edit ~/.ghpm.yaml.
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "View and edit ghpm settings",
+}
+
+var configGetReveal bool
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print the effective value of a setting",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		v, err := config.Get(args[0])
+		if err != nil {
+			return err
+		}
+		if !configGetReveal {
+			v = maskSecret(args[0], v)
+		}
+		fmt.Println(v)
+		return nil
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Write a setting to the config file",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.Set(args[0], args[1]); err != nil {
+			return err
+		}
+		fmt.Printf("Set %s in %s\n", args[0], config.Path())
+		return nil
+	},
+}
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List all settings and their effective values",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, k := range config.Keys() {
+			v, err := config.Get(k.Name)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "%s\t%v\n", k.Name, maskSecret(k.Name, v))
+		}
+		return w.Flush()
+	},
+}
+
+var configPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Print the config file location",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println(config.Path())
+	},
+}
+
+var configInitForce bool
+
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Write a commented default config file",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := config.InitFile(configInitForce)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %s\n", path)
+		return nil
+	},
+}
+
+// maskSecret hides the value of the setting called name if it is a
+// secret that is set.
+func maskSecret(name string, v any) any {
+	if name == "github_token" && v != "" {
+		return "********"
+	}
+	return v
+}
+
+func init() {
+	// config cannot import the packages that define these values.
+	config.SetValidator("on_conflict", func(v string) error {
+		_, err := ghops.ParseConflictPolicy(v)
+		return err
+	})
+	config.SetValidator("layout", func(v string) error {
+		_, err := ghops.ParseLayout(v)
+		return err
+	})
+	config.SetValidator("backend", github.ValidateBackend)
+
+	configGetCmd.Flags().BoolVar(&configGetReveal, "reveal", false, "print secrets such as github_token in clear")
+	configInitCmd.Flags().BoolVar(&configInitForce, "force", false, "overwrite an existing config file")
+
+	configCmd.AddCommand(configGetCmd, configSetCmd, configListCmd, configPathCmd, configInitCmd)
+	rootCmd.AddCommand(configCmd)
+}
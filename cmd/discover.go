@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sanurb/ghpm/internal/ghops"
+	"github.com/spf13/cobra"
+)
+
+// discoverFlags holds the --filter and --max-depth flags of the batch
+// commands.
+var discoverFlags struct {
+	filters  []string
+	maxDepth int
+	kinds    []string
+}
+
+// addDiscoverFlags adds the flags that select which local repositories a
+// batch command works on.
+func addDiscoverFlags(cmd *cobra.Command) {
+	f := cmd.Flags()
+	f.StringArrayVar(&discoverFlags.filters, "filter", nil, "only use repositories whose path matches `glob`; !glob skips matching directories (repeatable)")
+	f.IntVar(&discoverFlags.maxDepth, "max-depth", 0, "search at most `n` directories below the root (0 for no limit)")
+	f.StringSliceVar(&discoverFlags.kinds, "kind", nil, "only use repositories of these `kinds`: normal, worktree, submodule, bare or all (default normal,worktree)")
+}
+
+// discoverOptions returns the selection made with the discovery flags.
+// Unreadable directories are reported on stderr.
+func discoverOptions() (ghops.DiscoverOptions, error) {
+	kinds, err := ghops.ParseRepoKinds(discoverFlags.kinds)
+	if err != nil {
+		return ghops.DiscoverOptions{}, err
+	}
+	include, exclude := ghops.ParseFilters(discoverFlags.filters)
+	return ghops.DiscoverOptions{
+		MaxDepth: discoverFlags.maxDepth,
+		Include:  include,
+		Exclude:  exclude,
+		Kinds:    kinds,
+		Warn: func(msg string) {
+			fmt.Fprintln(os.Stderr, "warning:", msg)
+		},
+	}, nil
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sanurb/ghpm/internal/config"
+	"github.com/sanurb/ghpm/internal/ghops"
+	"github.com/sanurb/ghpm/internal/git"
+	"github.com/spf13/cobra"
+)
+
+var execOpts struct {
+	root       string
+	failFast   bool
+	onlyFailed bool
+}
+
+// execCmd runs a shell command in every local repository.
+var execCmd = &cobra.Command{
+	Use:   "exec [flags] -- <command> [args...]",
+	Short: "Run a command in every local repository",
+	Long: `Run a command in every git repository found under --root.
+
+Each repository's output is captured and printed as one block, followed by a
+summary table with the exit code and duration per repository. A failure in
+one repository does not stop the others unless --fail-fast is set.`,
+	Example: `  ghpm exec -- git pull --ff-only
+  ghpm exec --only-failed -- 'go build ./...'`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		command := joinCommand(args)
+		root := execOpts.root
+		if root == "" {
+			root = config.AppConfig.Root()
+		}
+		disc, err := discoverOptions()
+		if err != nil {
+			return err
+		}
+		ctx, plan := commandContext()
+		results, err := ghops.RunCommand(ctx, root, command, ghops.ExecOptions{
+			Jobs:     config.AppConfig.Jobs,
+			FailFast: execOpts.failFast,
+			Discover: disc,
+		})
+		if err != nil {
+			return err
+		}
+		if plan != nil {
+			printPlan(plan)
+			if outputFormat.Human() {
+				return nil
+			}
+			return writeResults(results)
+		}
+
+		failed, skipped := 0, 0
+		for _, r := range results {
+			switch {
+			case r.NotStarted():
+				skipped++
+			case r.Err != nil:
+				failed++
+			}
+		}
+
+		shown := results
+		if execOpts.onlyFailed {
+			shown = shown[:0:0]
+			for _, r := range results {
+				if r.Err != nil {
+					shown = append(shown, r)
+				}
+			}
+		}
+
+		if outputFormat.Human() {
+			printExecOutput(shown)
+			printExecSummary(shown)
+		} else if err := writeResults(shown); err != nil {
+			return err
+		}
+
+		if failed > 0 {
+			if skipped > 0 {
+				return fmt.Errorf("%q failed in %d of %d repositories (%d skipped)", command, failed, len(results), skipped)
+			}
+			return fmt.Errorf("%q failed in %d of %d repositories", command, failed, len(results))
+		}
+		return nil
+	},
+}
+
+func init() {
+	f := execCmd.Flags()
+	f.StringVar(&execOpts.root, "root", "", "directory to search for repositories (default: clone_root, or the current directory)")
+	f.BoolVar(&execOpts.failFast, "fail-fast", false, "stop starting new repositories after the first failure")
+	f.BoolVar(&execOpts.onlyFailed, "only-failed", false, "only show output and summary rows for failed repositories")
+	addDiscoverFlags(execCmd)
+
+	rootCmd.AddCommand(execCmd)
+}
+
+// printExecOutput prints each result's output as one block.
+func printExecOutput(results []ghops.Result) {
+	for _, r := range results {
+		if r.NotStarted() {
+			continue
+		}
+		fmt.Printf("==> %s\n", r.Name)
+		out := r.Output + r.Stderr
+		fmt.Print(out)
+		if out != "" && !strings.HasSuffix(out, "\n") {
+			fmt.Println()
+		}
+		fmt.Println()
+	}
+}
+
+// printExecSummary prints one row per result with its exit code and duration.
+func printExecSummary(results []ghops.Result) {
+	if len(results) == 0 {
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REPO\tEXIT\tDURATION")
+	for _, r := range results {
+		exit := fmt.Sprint(r.ExitCode())
+		if r.NotStarted() {
+			exit = "skipped"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", r.Name, exit, r.Duration.Round(time.Millisecond))
+	}
+	w.Flush()
+}
+
+// joinCommand turns the arguments after "--" back into a shell command.
+// A single argument is used verbatim so pipes and quoting survive; multiple
+// arguments are quoted individually.
+func joinCommand(args []string) string {
+	if len(args) == 1 {
+		return args[0]
+	}
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = git.ShellQuote(a)
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/sanurb/ghpm/internal/config"
+	"github.com/sanurb/ghpm/internal/ghops"
+	"github.com/sanurb/ghpm/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var exportOpts struct {
+	root string
+	file string
+}
+
+// exportCmd writes a workspace manifest describing the local checkouts.
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Write a workspace manifest describing the local repositories",
+	Long: `Write a workspace manifest listing every git repository under --root with its
+origin URL, other remotes, current branch and path relative to --root.
+"ghpm apply -f" recreates the same tree from it on another machine.
+
+The manifest is YAML, or JSON with -o json. Repositories without an origin
+remote cannot be cloned again and are left out with a warning.`,
+	Example: `  ghpm export -f workspace.yaml
+  ghpm export --root ~/src -o json > workspace.json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format := outputFormat
+		switch format {
+		case output.JSON, output.YAML:
+		case output.Table:
+			format = output.YAML
+		default:
+			return fmt.Errorf("export writes yaml or json, not %s", format)
+		}
+		root := exportOpts.root
+		if root == "" {
+			root = config.AppConfig.Root()
+		}
+		disc, err := discoverOptions()
+		if err != nil {
+			return err
+		}
+		m, warnings, err := ghops.ExportManifest(context.Background(), root, disc)
+		if err != nil {
+			return err
+		}
+		for _, w := range warnings {
+			fmt.Fprintln(os.Stderr, "warning:", w)
+		}
+
+		if exportOpts.file == "" || exportOpts.file == "-" {
+			return output.Write(os.Stdout, format, m, output.Rows{})
+		}
+		f, err := os.Create(exportOpts.file)
+		if err != nil {
+			return err
+		}
+		if err := output.Write(f, format, m, output.Rows{}); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to write manifest: %w", err)
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("failed to write manifest: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Exported %d repositories to %s.\n", len(m.Repos), exportOpts.file)
+		return nil
+	},
+}
+
+func init() {
+	f := exportCmd.Flags()
+	f.StringVar(&exportOpts.root, "root", "", "directory to search for repositories (default: clone_root, or the current directory)")
+	f.StringVarP(&exportOpts.file, "file", "f", "", "write the manifest to `path` instead of stdout")
+	addDiscoverFlags(exportCmd)
+
+	rootCmd.AddCommand(exportCmd)
+}
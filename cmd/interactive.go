@@ -12,9 +12,9 @@ func InteractiveCmd() error {
 	const pageSize = 10
 
 	p := tea.NewProgram(
-		ui.NewTuiModel(pageSize),  // pass a user-defined page size
-		tea.WithAltScreen(),       // alt screen
-		tea.WithMouseCellMotion(), // mouse motion
+		ui.NewTuiModel(pageSize).WithDryRun(dryRun), // pass a user-defined page size
+		tea.WithAltScreen(),                         // alt screen
+		tea.WithMouseCellMotion(),                   // mouse motion
 	)
 
 	if _, err := p.Run(); err != nil {
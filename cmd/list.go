@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sanurb/ghpm/internal/config"
+	"github.com/sanurb/ghpm/internal/ghops"
+	"github.com/spf13/cobra"
+)
+
+// listCmd groups commands that list repositories and organizations.
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List GitHub repositories, organizations or local checkouts",
+}
+
+var listReposOpts struct {
+	self bool
+	user string
+	org  string
+}
+
+// listReposCmd lists the repositories of the authenticated user, a user or
+// an organization.
+var listReposCmd = &cobra.Command{
+	Use:   "repos",
+	Short: "List your own, a user's or an org's repositories",
+	Example: `  ghpm list repos --self
+  ghpm list repos --org my-org -o json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		limit := config.AppConfig.RepoLimit
+		var listing ghops.Listing
+		var err error
+		switch {
+		case listReposOpts.self:
+			listing, err = ghops.ListSelfRepos(limit)
+		case listReposOpts.user != "":
+			listing, err = ghops.ListPublicRepos(listReposOpts.user, limit)
+		default:
+			listing, err = ghops.ListOrgRepos(listReposOpts.org, limit)
+		}
+		if err != nil {
+			return err
+		}
+		if w := listing.Warning(); w != "" {
+			fmt.Fprintln(os.Stderr, "warning:", w)
+		}
+		return writeRepos(listing.Repos)
+	},
+}
+
+// listOrgsCmd lists the organizations of the authenticated user.
+var listOrgsCmd = &cobra.Command{
+	Use:   "orgs",
+	Short: "List the organizations you belong to",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		orgs, err := ghops.ListUserOrgs()
+		if err != nil {
+			return err
+		}
+		return writeOrgs(orgs)
+	},
+}
+
+var listLocalOpts struct {
+	root string
+}
+
+// listLocalCmd lists the git checkouts under a directory.
+var listLocalCmd = &cobra.Command{
+	Use:   "local",
+	Short: "List the git repositories under a directory",
+	Example: `  ghpm list local --root ~/src
+  ghpm list local -o tsv | cut -f1`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		root := listLocalOpts.root
+		if root == "" {
+			root = config.AppConfig.Root()
+		}
+		disc, err := discoverOptions()
+		if err != nil {
+			return err
+		}
+		repos, err := ghops.LocalRepos(root, disc)
+		if err != nil {
+			return err
+		}
+		return writeLocalRepos(repos)
+	},
+}
+
+func init() {
+	f := listReposCmd.Flags()
+	f.BoolVar(&listReposOpts.self, "self", false, "list the authenticated user's repositories")
+	f.StringVar(&listReposOpts.user, "user", "", "list the public repositories of `name`")
+	f.StringVar(&listReposOpts.org, "org", "", "list the repositories of organization `login`")
+	listReposCmd.MarkFlagsOneRequired("self", "user", "org")
+	listReposCmd.MarkFlagsMutuallyExclusive("self", "user", "org")
+
+	listLocalCmd.Flags().StringVar(&listLocalOpts.root, "root", "", "directory to search for repositories (default: clone_root, or the current directory)")
+	addDiscoverFlags(listLocalCmd)
+
+	listCmd.AddCommand(listReposCmd, listOrgsCmd, listLocalCmd)
+	rootCmd.AddCommand(listCmd)
+}
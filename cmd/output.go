@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sanurb/ghpm/internal/ghops"
+	"github.com/sanurb/ghpm/internal/github"
+	"github.com/sanurb/ghpm/internal/output"
+)
+
+// outputFlag is the --output flag; outputFormat is its parsed value, set
+// before any subcommand runs.
+var (
+	outputFlag   string
+	outputFormat = output.Table
+)
+
+// streamTo returns fn if results are printed for people as they finish,
+// and nil when the output is meant for another program.
+func streamTo(fn func(ghops.Result)) func(ghops.Result) {
+	if outputFormat.Human() {
+		return fn
+	}
+	return nil
+}
+
+// writeResults prints batch results in the --output format.
+func writeResults(results []ghops.Result) error {
+	records := make([]ghops.ResultRecord, len(results))
+	rows := output.Rows{Columns: []string{"REPO", "STATUS", "EXIT", "DURATION", "ERROR"}}
+	for i, r := range results {
+		records[i] = r.Record()
+		errMsg, _, _ := strings.Cut(records[i].Error, "\n")
+		rows.Rows = append(rows.Rows, []string{
+			r.Name, r.Status, fmt.Sprint(r.ExitCode()), r.Duration.Round(time.Millisecond).String(), errMsg,
+		})
+	}
+	return output.Write(os.Stdout, outputFormat, records, rows)
+}
+
+// writeRepos prints a repository listing in the --output format.
+func writeRepos(repos []github.Repo) error {
+	rows := output.Rows{Columns: []string{"NAME", "OWNER", "VISIBILITY", "LANGUAGE", "FLAGS", "PUSHED"}}
+	for _, r := range repos {
+		var flags []string
+		if r.IsFork {
+			flags = append(flags, "fork")
+		}
+		if r.IsArchived {
+			flags = append(flags, "archived")
+		}
+		pushed := ""
+		if !r.PushedAt.IsZero() {
+			pushed = r.PushedAt.Format(time.DateOnly)
+		}
+		rows.Rows = append(rows.Rows, []string{r.Name, r.Owner, r.Visibility, r.Language, strings.Join(flags, ","), pushed})
+	}
+	if repos == nil {
+		repos = []github.Repo{}
+	}
+	return output.Write(os.Stdout, outputFormat, repos, rows)
+}
+
+// writeOrgs prints an organization listing in the --output format.
+func writeOrgs(orgs []github.Org) error {
+	rows := output.Rows{Columns: []string{"LOGIN", "NAME"}}
+	for _, o := range orgs {
+		rows.Rows = append(rows.Rows, []string{o.Login, o.Name})
+	}
+	if orgs == nil {
+		orgs = []github.Org{}
+	}
+	return output.Write(os.Stdout, outputFormat, orgs, rows)
+}
+
+// writeLocalRepos prints local checkouts in the --output format.
+func writeLocalRepos(repos []ghops.LocalRepo) error {
+	rows := output.Rows{Columns: []string{"PATH", "KIND", "REMOTE"}}
+	for _, r := range repos {
+		rows.Rows = append(rows.Rows, []string{r.Path, string(r.Kind), r.Remote})
+	}
+	if repos == nil {
+		repos = []ghops.LocalRepo{}
+	}
+	return output.Write(os.Stdout, outputFormat, repos, rows)
+}
+
+// writeStatuses prints repository statuses in the --output format.
+func writeStatuses(statuses []ghops.RepoStatus) error {
+	now := time.Now()
+	records := make([]ghops.StatusRecord, len(statuses))
+	rows := output.Rows{Columns: ghops.StatusColumns}
+	for i, s := range statuses {
+		records[i] = s.Record()
+		rows.Rows = append(rows.Rows, s.Row(now))
+	}
+	return output.Write(os.Stdout, outputFormat, records, rows)
+}
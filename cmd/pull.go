@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"github.com/sanurb/ghpm/internal/config"
+	"github.com/sanurb/ghpm/internal/ghops"
+	"github.com/sanurb/ghpm/internal/git"
+	"github.com/spf13/cobra"
+)
+
+var pullOpts struct {
+	root   string
+	rebase bool
+	ffOnly bool
+}
+
+// pullCmd pulls every local repository from its upstream.
+var pullCmd = &cobra.Command{
+	Use:   "pull",
+	Short: "Pull every local repository from its upstream",
+	Long: `Pull the current branch of every git repository under --root from its upstream.
+
+Repositories with uncommitted changes, a detached HEAD or a branch without an
+upstream are skipped and reported. Without --rebase or --ff-only, git's own
+pull.rebase/pull.ff settings apply.`,
+	Example: `  ghpm pull --ff-only
+  ghpm pull --rebase --root ~/src`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		root := pullOpts.root
+		if root == "" {
+			root = config.AppConfig.Root()
+		}
+		mode := git.PullDefault
+		switch {
+		case pullOpts.rebase:
+			mode = git.PullRebase
+		case pullOpts.ffOnly:
+			mode = git.PullFFOnly
+		}
+		disc, err := discoverOptions()
+		if err != nil {
+			return err
+		}
+		ctx, plan := commandContext()
+		results, err := ghops.Pull(ctx, root, mode, ghops.ExecOptions{
+			Jobs:     config.AppConfig.Jobs,
+			OnResult: streamTo(printBatchResult),
+			Discover: disc,
+		})
+		if err != nil {
+			return err
+		}
+		printPlan(plan)
+		return reportBatch("pull", results)
+	},
+}
+
+func init() {
+	f := pullCmd.Flags()
+	f.StringVar(&pullOpts.root, "root", "", "directory to search for repositories (default: clone_root, or the current directory)")
+	f.BoolVar(&pullOpts.rebase, "rebase", false, "rebase local commits onto the upstream")
+	f.BoolVar(&pullOpts.ffOnly, "ff-only", false, "only fast-forward; fail repositories that have diverged")
+	addDiscoverFlags(pullCmd)
+	pullCmd.MarkFlagsMutuallyExclusive("rebase", "ff-only")
+
+	rootCmd.AddCommand(pullCmd)
+}
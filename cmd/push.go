@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sanurb/ghpm/internal/config"
+	"github.com/sanurb/ghpm/internal/ghops"
+	"github.com/spf13/cobra"
+)
+
+var pushOpts struct {
+	root string
+}
+
+// pushCmd pushes every local repository to its upstream.
+var pushCmd = &cobra.Command{
+	Use:   "push",
+	Short: "Push every local repository to its upstream",
+	Long: `Push the current branch of every git repository under --root to its upstream.
+
+Repositories with uncommitted changes, a detached HEAD or a branch without an
+upstream are skipped and reported. With --dry-run nothing is pushed; the
+commits each repository would push are listed instead.`,
+	Example: `  ghpm push --dry-run
+  ghpm push --root ~/src`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		root := pushOpts.root
+		if root == "" {
+			root = config.AppConfig.Root()
+		}
+		disc, err := discoverOptions()
+		if err != nil {
+			return err
+		}
+		ctx, plan := commandContext()
+		results, err := ghops.Push(ctx, root, ghops.ExecOptions{
+			Jobs:     config.AppConfig.Jobs,
+			OnResult: streamTo(printBatchResult),
+			Discover: disc,
+		})
+		if err != nil {
+			return err
+		}
+		printPlan(plan)
+		return reportBatch("push", results)
+	},
+}
+
+func init() {
+	f := pushCmd.Flags()
+	f.StringVar(&pushOpts.root, "root", "", "directory to search for repositories (default: clone_root, or the current directory)")
+	addDiscoverFlags(pushCmd)
+
+	rootCmd.AddCommand(pushCmd)
+}
+
+// printBatchResult prints one line per finished repository, followed by its
+// output when it was skipped, failed or is a dry run.
+func printBatchResult(r ghops.Result) {
+	mark := "✓"
+	switch {
+	case r.Err != nil:
+		fmt.Printf("✗ %s [%s]: %v\n", r.Name, r.Status, r.Err)
+		return
+	case r.Status == ghops.StatusSkipped:
+		mark = "!"
+	}
+	fmt.Printf("%s %s [%s] (%s)\n", mark, r.Name, r.Status, r.Duration.Round(100*time.Millisecond))
+	if r.Status == ghops.StatusSkipped || r.Status == ghops.StatusWouldPush || r.Status == ghops.StatusWouldPull {
+		for _, line := range strings.Split(strings.TrimRight(r.Output, "\n"), "\n") {
+			fmt.Printf("    %s\n", line)
+		}
+	}
+}
+
+// reportBatch prints the summary of a push or pull, or the results in the
+// --output format, and returns an error if any repository failed.
+func reportBatch(op string, results []ghops.Result) error {
+	failed := 0
+	var skipped []ghops.Result
+	for _, r := range results {
+		switch {
+		case r.Err != nil:
+			failed++
+		case r.Status == ghops.StatusSkipped:
+			skipped = append(skipped, r)
+		}
+	}
+
+	if !outputFormat.Human() {
+		if err := writeResults(results); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("\n%d repositories: %s.\n", len(results), ghops.Summary(results))
+	}
+	if len(skipped) > 0 && outputFormat.Human() {
+		fmt.Println("\nSkipped:")
+		for _, r := range skipped {
+			fmt.Printf("  %s: %s\n", r.Name, strings.TrimSpace(r.Output))
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d repositories failed to %s", failed, len(results), op)
+	}
+	return nil
+}
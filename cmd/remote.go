@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sanurb/ghpm/internal/config"
+	"github.com/sanurb/ghpm/internal/ghops"
+	"github.com/sanurb/ghpm/internal/remoteurl"
+	"github.com/spf13/cobra"
+)
+
+// remoteCmd groups commands that manage the remotes of local repositories.
+var remoteCmd = &cobra.Command{
+	Use:   "remote",
+	Short: "Manage the remotes of local repositories",
+}
+
+var remoteConvertOpts struct {
+	root     string
+	remote   string
+	to       string
+	noVerify bool
+}
+
+// remoteConvertCmd switches remotes between SSH and HTTPS.
+var remoteConvertCmd = &cobra.Command{
+	Use:   "convert --to ssh|https",
+	Short: "Switch the remote of every local repository to SSH or HTTPS",
+	Long: `Rewrite a remote of every git repository under --root to use another protocol,
+keeping its host, owner and repository name.
+
+Before a URL is changed, "git ls-remote" checks that the new URL answers;
+repositories that fail the check are left unchanged. --dry-run prints the
+changes (and runs the checks) without applying them.`,
+	Example: `  ghpm remote convert --to ssh --dry-run
+  ghpm remote convert --to https --remote upstream --root ~/src`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		to, err := remoteurl.ParseProtocol(remoteConvertOpts.to)
+		if err != nil {
+			return err
+		}
+		if to != remoteurl.SSH && to != remoteurl.HTTPS {
+			return fmt.Errorf("--to must be ssh or https, not %q", remoteConvertOpts.to)
+		}
+		root := remoteConvertOpts.root
+		if root == "" {
+			root = config.AppConfig.Root()
+		}
+
+		disc, err := discoverOptions()
+		if err != nil {
+			return err
+		}
+		ctx, plan := commandContext()
+		results, err := ghops.ConvertRemotes(ctx, root, ghops.ConvertOptions{
+			Remote: remoteConvertOpts.remote,
+			To:     to,
+			Verify: !remoteConvertOpts.noVerify,
+		}, ghops.ExecOptions{Jobs: config.AppConfig.Jobs, Discover: disc})
+		if err != nil {
+			return err
+		}
+
+		failed := 0
+		for _, r := range results {
+			if r.Err != nil {
+				failed++
+			}
+		}
+		printPlan(plan)
+		if !outputFormat.Human() {
+			if err := writeResults(results); err != nil {
+				return err
+			}
+		} else {
+			printConvertResults(results)
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d remotes could not be converted", failed, len(results))
+		}
+		return nil
+	},
+}
+
+func init() {
+	f := remoteConvertCmd.Flags()
+	f.StringVar(&remoteConvertOpts.to, "to", "", "protocol to switch to: ssh or https")
+	f.StringVar(&remoteConvertOpts.remote, "remote", "origin", "`name` of the remote to convert")
+	f.StringVar(&remoteConvertOpts.root, "root", "", "directory to search for repositories (default: clone_root, or the current directory)")
+	f.BoolVar(&remoteConvertOpts.noVerify, "no-verify", false, "skip checking the new URLs with git ls-remote")
+	addDiscoverFlags(remoteConvertCmd)
+	remoteConvertCmd.MarkFlagRequired("to")
+
+	remoteCmd.AddCommand(remoteConvertCmd)
+	rootCmd.AddCommand(remoteCmd)
+}
+
+// printConvertResults prints the changed and failed remotes, then a summary.
+func printConvertResults(results []ghops.Result) {
+	for _, r := range results {
+		switch {
+		case r.Err != nil:
+			msg, _, _ := strings.Cut(r.Err.Error(), "\n")
+			fmt.Printf("✗ %s: %s\n", r.Name, msg)
+			if r.Output != "" {
+				fmt.Print(indent(r.Output))
+			}
+		case r.Status == ghops.StatusConverted || r.Status == ghops.StatusWouldConvert:
+			fmt.Printf("%s [%s]\n", r.Name, r.Status)
+			fmt.Print(indent(r.Output))
+		}
+	}
+	fmt.Printf("\n%d repositories: %s.\n", len(results), ghops.Summary(results))
+}
+
+// indent prefixes every line of s with four spaces.
+func indent(s string) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	return "    " + strings.Join(lines, "\n    ") + "\n"
+}
@@ -1,15 +1,42 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"os"
 
+	"github.com/sanurb/ghpm/internal/config"
+	"github.com/sanurb/ghpm/internal/git"
+	"github.com/sanurb/ghpm/internal/output"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
+// configFile is the --config flag; empty means ~/.ghpm.yaml.
+var configFile string
+
+// dryRun is the --dry-run flag: print what would be done instead of doing it.
+var dryRun bool
+
 // rootCmd is the main Cobra command.
 var rootCmd = &cobra.Command{
 	Use:   "ghpm",
 	Short: "ghpm - GitHub Project Manager",
+	// main reports the returned error; don't print it (and usage) twice.
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	// Load ~/.ghpm.yaml, GHPM_* variables and flags before any subcommand.
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.LoadConfig(configFile); err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		f, err := output.ParseFormat(outputFlag)
+		if err != nil {
+			return err
+		}
+		outputFormat = f
+		return nil
+	},
 	// On no subcommand, launch the interactive TUI.
 	Run: func(cmd *cobra.Command, args []string) {
 		if err := InteractiveCmd(); err != nil {
@@ -18,6 +45,57 @@ var rootCmd = &cobra.Command{
 	},
 }
 
+func init() {
+	pf := rootCmd.PersistentFlags()
+	pf.StringVar(&configFile, "config", "", "config file (default: ~/.ghpm.yaml)")
+	pf.StringVarP(&outputFlag, "output", "o", "table", "output format for listings and results: table, json, yaml or tsv")
+	pf.BoolVar(&dryRun, "dry-run", false, "print the commands that would change anything instead of running them")
+	pf.IntP("jobs", "j", config.DefaultJobs, "number of repositories to process in parallel")
+	viper.BindPFlag("jobs", pf.Lookup("jobs"))
+	pf.Int("limit", 0, "maximum number of repositories to list (0 for no limit)")
+	viper.BindPFlag("repo_limit", pf.Lookup("limit"))
+	pf.String("layout", "flat", "where clones go: flat, owner, host, or a template like '{{.Owner}}/{{.Name}}'")
+	viper.BindPFlag("layout", pf.Lookup("layout"))
+}
+
+// commandContext returns the context subcommands run in. With --dry-run it
+// carries a plan that mutating operations record their commands in; the
+// plan is nil otherwise.
+func commandContext() (context.Context, *git.Plan) {
+	ctx := context.Background()
+	if !dryRun {
+		return ctx, nil
+	}
+	plan := &git.Plan{}
+	return git.WithPlan(ctx, plan), plan
+}
+
+// printPlan prints the commands recorded by a dry run, if any. They go to
+// stderr when stdout carries --output for another program.
+func printPlan(plan *git.Plan) {
+	if plan == nil {
+		return
+	}
+	w := os.Stdout
+	if !outputFormat.Human() {
+		w = os.Stderr
+	}
+	steps := plan.Steps()
+	if len(steps) == 0 {
+		fmt.Fprintln(w, "\nDry run: nothing would change.")
+		return
+	}
+	fmt.Fprintln(w, "\nDry run: nothing was changed. Commands that would run:")
+	dir := ""
+	for i, s := range steps {
+		if i == 0 || s.Dir != dir {
+			dir = s.Dir
+			fmt.Fprintf(w, "  in %s:\n", dir)
+		}
+		fmt.Fprintf(w, "    %s\n", s)
+	}
+}
+
 // Execute runs the root command.
 func Execute() error {
 	return rootCmd.Execute()
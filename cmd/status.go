@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sanurb/ghpm/internal/config"
+	"github.com/sanurb/ghpm/internal/ghops"
+	"github.com/spf13/cobra"
+)
+
+var statusOpts struct {
+	root   string
+	sort   string
+	filter ghops.StatusFilter
+}
+
+// statusCmd shows a status table for every local repository.
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show branch, sync and working tree status of local repositories",
+	Long: `Show the status of every git repository under --root: its current branch,
+how far it is ahead of and behind its upstream, uncommitted and untracked
+changes, stashes, and the age of its last commit.
+
+Nothing is fetched, so ahead/behind counts are as of each repository's last
+fetch. The filter flags combine with OR: --dirty --ahead shows repositories
+that are dirty or ahead. --unpushed shows every repository holding work that
+exists nowhere else (changes, stashes, unpushed commits, detached HEAD or no
+upstream).`,
+	Example: `  ghpm status
+  ghpm status --sort age
+  ghpm status --unpushed --root ~/src`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		root := statusOpts.root
+		if root == "" {
+			root = config.AppConfig.Root()
+		}
+		disc, err := discoverOptions()
+		if err != nil {
+			return err
+		}
+		statuses, err := ghops.LocalStatus(context.Background(), root, disc, config.AppConfig.Jobs)
+		if err != nil {
+			return err
+		}
+		if err := ghops.SortStatuses(statuses, statusOpts.sort); err != nil {
+			return err
+		}
+		statuses = statusOpts.filter.Apply(statuses)
+		if len(statuses) == 0 && outputFormat.Human() {
+			fmt.Fprintln(os.Stderr, "No matching repositories.")
+			return nil
+		}
+		return writeStatuses(statuses)
+	},
+}
+
+func init() {
+	f := statusCmd.Flags()
+	f.StringVar(&statusOpts.root, "root", "", "directory to search for repositories (default: clone_root, or the current directory)")
+	f.StringVar(&statusOpts.sort, "sort", "name", "sort by `key`: "+strings.Join(ghops.StatusSortKeys, ", "))
+	f.StringVar(&statusOpts.filter.Match, "match", "", "only show repositories whose path or name matches `glob`")
+	f.BoolVar(&statusOpts.filter.Dirty, "dirty", false, "show repositories with uncommitted or untracked changes")
+	f.BoolVar(&statusOpts.filter.Ahead, "ahead", false, "show repositories with commits not on their upstream")
+	f.BoolVar(&statusOpts.filter.Behind, "behind", false, "show repositories behind their upstream")
+	f.BoolVar(&statusOpts.filter.Detached, "detached", false, "show repositories with a detached HEAD")
+	f.BoolVar(&statusOpts.filter.Stashed, "stashed", false, "show repositories with stashes")
+	f.BoolVar(&statusOpts.filter.Unpushed, "unpushed", false, "show repositories holding work that exists nowhere else")
+	addDiscoverFlags(statusCmd)
+
+	rootCmd.AddCommand(statusCmd)
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sanurb/ghpm/internal/config"
+	"github.com/sanurb/ghpm/internal/ghops"
+	"github.com/spf13/cobra"
+)
+
+var syncOpts struct {
+	org  string
+	root string
+}
+
+// syncCmd mirrors an organization into a local directory.
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Clone missing repositories and fast-forward existing ones",
+	Long: `Mirror an organization's repositories under --root.
+
+Repositories without a local checkout are cloned. Existing checkouts are
+fetched and fast-forwarded to their upstream when they are clean and have not
+diverged; anything else (local changes, diverged history, detached HEAD, no
+upstream) is left untouched and reported as needing attention.`,
+	Example: `  ghpm sync --org my-org --root ~/src/my-org`,
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		listing, err := ghops.ListOrgRepos(syncOpts.org, config.AppConfig.RepoLimit)
+		if err != nil {
+			return err
+		}
+		if w := listing.Warning(); w != "" {
+			fmt.Fprintln(os.Stderr, "warning:", w)
+		}
+
+		root := syncOpts.root
+		if root == "" {
+			root = config.AppConfig.Root()
+		}
+		disc, err := discoverOptions()
+		if err != nil {
+			return err
+		}
+		ctx, plan := commandContext()
+		if plan != nil {
+			plan.Add(".", "mkdir", "-p", root)
+		} else if err := os.MkdirAll(root, 0o755); err != nil {
+			return err
+		}
+		layout, err := ghops.ParseLayout(config.AppConfig.Layout)
+		if err != nil {
+			return err
+		}
+
+		results, err := ghops.Sync(ctx, listing.Repos, ghops.SyncOptions{
+			Root:     root,
+			Owner:    syncOpts.org,
+			Layout:   layout,
+			Jobs:     config.AppConfig.Jobs,
+			Discover: disc,
+			Hooks:    config.AppConfig.Hooks,
+			OnResult: streamTo(printSyncResult),
+		})
+		if err != nil {
+			return err
+		}
+
+		failed := 0
+		var attention []ghops.Result
+		for _, r := range results {
+			switch {
+			case r.Err != nil:
+				failed++
+			case r.Status == ghops.StatusAttention:
+				attention = append(attention, r)
+			}
+		}
+
+		printPlan(plan)
+		if !outputFormat.Human() {
+			if err := writeResults(results); err != nil {
+				return err
+			}
+		} else {
+			fmt.Printf("\n%d repositories: %s.\n", len(results), ghops.Summary(results))
+		}
+		if len(attention) > 0 && outputFormat.Human() {
+			fmt.Println("\nNeeds attention:")
+			for _, r := range attention {
+				fmt.Printf("  %s: %s\n", r.Name, strings.TrimSpace(r.Output))
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d repositories failed to sync", failed, len(results))
+		}
+		return nil
+	},
+}
+
+func init() {
+	f := syncCmd.Flags()
+	f.StringVar(&syncOpts.org, "org", "", "organization `login` to mirror")
+	f.StringVar(&syncOpts.root, "root", "", "directory holding the checkouts (default: clone_root, or the current directory)")
+	addDiscoverFlags(syncCmd)
+	syncCmd.MarkFlagRequired("org")
+
+	rootCmd.AddCommand(syncCmd)
+}
+
+func printSyncResult(r ghops.Result) {
+	mark := "✓"
+	switch {
+	case r.Err != nil:
+		fmt.Printf("✗ %s [%s]: %v\n", r.Name, r.Status, r.Err)
+		return
+	case r.Status == ghops.StatusAttention || r.Status == ghops.StatusLocalOnly:
+		mark = "!"
+	}
+	fmt.Printf("%s %s [%s] (%s)\n", mark, r.Name, r.Status, r.Duration.Round(100*time.Millisecond))
+}
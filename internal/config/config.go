@@ -1,7 +1,10 @@
 package config
 
 import (
+	"errors"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/spf13/viper"
 )
@@ -9,24 +12,88 @@ import (
 type Config struct {
 	GitHubToken string `mapstructure:"github_token"`
 	DefaultUser string `mapstructure:"default_user"`
+	// CloneRoot is the directory repositories are cloned into and
+	// searched for by batch operations. Empty means the current directory.
+	CloneRoot string `mapstructure:"clone_root"`
+	// Jobs is the number of repositories processed in parallel.
+	Jobs int `mapstructure:"jobs"`
+	// RepoLimit caps how many repositories a listing fetches; 0 means
+	// no limit.
+	RepoLimit int `mapstructure:"repo_limit"`
+	// OnConflict is what cloning does when the destination directory
+	// already exists: skip, pull, fetch, reclone or fail.
+	OnConflict string `mapstructure:"on_conflict"`
+	// Layout is where clones go under CloneRoot: "flat", "owner",
+	// "host" or a template such as "{{.Owner}}/{{.Name}}".
+	Layout string `mapstructure:"layout"`
+	// Backend selects how ghpm talks to GitHub: "gh" (the GitHub CLI),
+	// "api" (the REST API, authenticated with GitHubToken or GITHUB_TOKEN)
+	// or "auto" (gh when installed, the API otherwise).
+	Backend string `mapstructure:"backend"`
+	// Hooks are commands run in a repository right after it is cloned.
+	Hooks []Hook `mapstructure:"hooks"`
 }
 
-var AppConfig Config
+// Hook is a post-clone hook: commands run in a freshly cloned repository
+// that matches all of the hook's conditions. Within a condition any entry
+// may match; a hook without conditions runs for every repository.
+type Hook struct {
+	// Match lists globs for the repository name.
+	Match []string `mapstructure:"match"`
+	// Language lists primary languages, compared case-insensitively.
+	Language []string `mapstructure:"language"`
+	// Files lists paths (or globs) relative to the checkout, e.g. "go.mod".
+	Files []string `mapstructure:"files"`
+	// Run lists the commands, run in order through "sh -c".
+	Run []string `mapstructure:"run"`
+}
+
+// DefaultJobs is the default number of repositories processed in parallel.
+const DefaultJobs = 4
+
+// EnvPrefix prefixes the environment variables that override config keys,
+// e.g. GHPM_DEFAULT_USER for default_user.
+const EnvPrefix = "GHPM"
 
-func LoadConfig() error {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return err
+var AppConfig = Config{Jobs: DefaultJobs}
+
+// LoadConfig reads the config file into AppConfig. path selects the file;
+// when empty, ~/.ghpm.yaml is used. A missing file is not an error: the
+// defaults, GHPM_* environment variables and bound flags still apply.
+func LoadConfig(path string) error {
+	if path != "" {
+		viper.SetConfigFile(path)
+	} else {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		viper.AddConfigPath(home)
+		viper.SetConfigName(".ghpm")
+		viper.SetConfigType("yaml")
 	}
-	viper.AddConfigPath(home)
-	viper.SetConfigName(".ghpm")
-	viper.SetConfigType("yaml")
 
-	// Set default values
+	viper.SetEnvPrefix(EnvPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	viper.AutomaticEnv()
+
+	// Set default values. Every key needs one so that environment
+	// variables are picked up by Unmarshal.
+	viper.SetDefault("github_token", "")
 	viper.SetDefault("default_user", "")
+	viper.SetDefault("clone_root", "")
+	viper.SetDefault("jobs", DefaultJobs)
+	viper.SetDefault("repo_limit", 0)
+	viper.SetDefault("on_conflict", "skip")
+	viper.SetDefault("layout", "flat")
+	viper.SetDefault("backend", "auto")
+	viper.SetDefault("hooks", []Hook{})
 
 	if err := viper.ReadInConfig(); err != nil {
-		return err
+		var notFound viper.ConfigFileNotFoundError
+		if !errors.As(err, &notFound) && !(path != "" && errors.Is(err, os.ErrNotExist)) {
+			return err
+		}
 	}
 	return viper.Unmarshal(&AppConfig)
 }
@@ -34,3 +101,18 @@ func LoadConfig() error {
 func SaveConfig() error {
 	return viper.WriteConfig()
 }
+
+// Root returns the directory batch operations work in: CloneRoot with a
+// leading "~" expanded, or "." when no clone root is configured.
+func (c Config) Root() string {
+	root := c.CloneRoot
+	if root == "" {
+		return "."
+	}
+	if root == "~" || strings.HasPrefix(root, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			root = filepath.Join(home, root[1:])
+		}
+	}
+	return root
+}
@@ -0,0 +1,203 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Key describes a setting of Config as it appears in the config file.
+type Key struct {
+	Name string       // e.g. "default_user"
+	Kind reflect.Kind // Go kind of the Config field
+}
+
+// Keys returns the settings of Config, sorted by name.
+func Keys() []Key {
+	t := reflect.TypeOf(Config{})
+	keys := make([]Key, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := f.Tag.Get("mapstructure")
+		if name == "" || name == "-" {
+			continue
+		}
+		keys = append(keys, Key{Name: name, Kind: f.Type.Kind()})
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Name < keys[j].Name })
+	return keys
+}
+
+// LookupKey returns the setting called name, or an error listing the valid
+// keys if there is none.
+func LookupKey(name string) (Key, error) {
+	var names []string
+	for _, k := range Keys() {
+		if k.Name == name {
+			return k, nil
+		}
+		names = append(names, k.Name)
+	}
+	return Key{}, fmt.Errorf("unknown config key %q (valid keys: %s)", name, strings.Join(names, ", "))
+}
+
+// Parse converts a command-line value to the type of the key.
+func (k Key) Parse(value string) (any, error) {
+	switch k.Kind {
+	case reflect.String:
+		return value, nil
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be an integer, got %q", k.Name, value)
+		}
+		return n, nil
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be true or false, got %q", k.Name, value)
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("%s is a structured setting; edit %s directly", k.Name, Path())
+	}
+}
+
+// validators holds the checks registered with SetValidator, by key name.
+var validators = map[string]func(value string) error{}
+
+// SetValidator makes Set check values of the setting called name with fn
+// before writing them. It lets the packages that give a setting its
+// meaning, which config cannot import, reject values they do not accept.
+func SetValidator(name string, fn func(value string) error) {
+	validators[name] = fn
+}
+
+// Get returns the effective value of the setting called name, after the
+// config file, environment and flags have been applied.
+func Get(name string) (any, error) {
+	if _, err := LookupKey(name); err != nil {
+		return nil, err
+	}
+	return viper.Get(name), nil
+}
+
+// Set validates value against the setting called name, including any check
+// registered with SetValidator, and writes it to the config file, creating
+// the file if needed. Only the file's own contents are written back, so
+// environment overrides never leak into it. Comments in the file are not
+// preserved.
+func Set(name, value string) error {
+	key, err := LookupKey(name)
+	if err != nil {
+		return err
+	}
+	v, err := key.Parse(value)
+	if err != nil {
+		return err
+	}
+	if validate := validators[name]; validate != nil {
+		if err := validate(value); err != nil {
+			return err
+		}
+	}
+
+	path := Path()
+	file := viper.New()
+	file.SetConfigFile(path)
+	file.SetConfigType("yaml")
+	if err := file.ReadInConfig(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	file.Set(name, v)
+	if err := file.WriteConfigAs(path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	viper.Set(name, v)
+	return viper.Unmarshal(&AppConfig)
+}
+
+// Path returns the config file in use, or ~/.ghpm.yaml if none was found.
+func Path() string {
+	if f := viper.ConfigFileUsed(); f != "" {
+		return f
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".ghpm.yaml"
+	}
+	return filepath.Join(home, ".ghpm.yaml")
+}
+
+// InitFile writes a commented default config file to Path(). An existing
+// file is only replaced when force is set.
+func InitFile(force bool) (string, error) {
+	path := Path()
+	if _, err := os.Stat(path); err == nil && !force {
+		return path, fmt.Errorf("%s already exists (use --force to overwrite)", path)
+	}
+	if err := os.WriteFile(path, []byte(defaultFile), 0o600); err != nil {
+		return path, err
+	}
+	return path, nil
+}
+
+// defaultFile is the config written by InitFile. Keep it in sync with Config.
+const defaultFile = `# ghpm configuration.
+# Every key can be overridden with a GHPM_<KEY> environment variable,
+# e.g. GHPM_JOBS=8.
+
+# How to talk to GitHub: "gh" (the GitHub CLI), "api" (the REST API) or
+# "auto" (gh when installed, the API otherwise).
+backend: auto
+
+# Token for GitHub. Passed to gh as GH_TOKEN; the API backend falls back
+# to GITHUB_TOKEN when empty. Leave empty to use gh's own login.
+github_token: ""
+
+# Username prefilled in the TUI's username prompts.
+default_user: ""
+
+# Directory repositories are cloned into and searched for by batch
+# commands. Empty means the current directory; "~" is expanded.
+clone_root: ""
+
+# Number of repositories processed in parallel.
+jobs: 4
+
+# Maximum number of repositories fetched per listing; 0 means no limit.
+repo_limit: 0
+
+# What cloning does when a repository's directory already exists:
+# skip, pull (fast-forward), fetch, reclone (delete and clone again) or
+# fail. reclone never deletes a checkout with uncommitted, untracked,
+# stashed or unpushed work. Directories holding something else are always
+# reported as conflicts.
+on_conflict: skip
+
+# Where clones go under clone_root: "flat" (name), "owner" (owner/name),
+# "host" (github.com/owner/name) or a template using .Host, .Owner and
+# .Name, e.g. "{{.Owner}}/{{.Name}}".
+layout: flat
+
+# Commands run in a repository right after it is cloned. A hook runs when
+# the repository matches all of the conditions it sets (match: name globs,
+# language: primary languages, files: paths in the checkout); any entry
+# of a condition may match.
+hooks: []
+#  - files: [go.mod]
+#    run: [go mod download]
+#  - files: [package-lock.json]
+#    run: [npm ci]
+#  - match: ["svc-*"]
+#    files: [.pre-commit-config.yaml]
+#    run: [pre-commit install]
+`
@@ -0,0 +1,306 @@
+package ghops
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sanurb/ghpm/internal/config"
+	"github.com/sanurb/ghpm/internal/git"
+	"github.com/sanurb/ghpm/internal/github"
+	"github.com/sanurb/ghpm/internal/manifest"
+	"github.com/sanurb/ghpm/internal/remoteurl"
+)
+
+// WorkspaceRepo is a repository of a workspace manifest, resolved to the
+// repository to clone and the directory to check it out in.
+type WorkspaceRepo struct {
+	Repo github.Repo // SSHUrl is the URL cloned from
+	// Path is the checkout's directory relative to the workspace root.
+	Path    string
+	Branch  string            // checked out after cloning, if set
+	Remotes map[string]string // added besides origin
+	Hooks   []string          // run after cloning
+}
+
+// ResolveManifest lists the sources of m, filters them, and merges in the
+// repos of m: a repo naming a repository a source selected overrides its
+// settings, and any other repo is added. Repositories without an explicit
+// path are placed with layout. It returns the repositories in manifest
+// order, together with warnings about truncated listings.
+func ResolveManifest(m *manifest.Manifest, layout *Layout, limit int) ([]WorkspaceRepo, []string, error) {
+	var (
+		repos    []WorkspaceRepo
+		warnings []string
+		index    = map[string]int{} // lowercase "owner/name" -> position in repos
+	)
+	for _, s := range m.Sources {
+		listing, err := listSource(s, limit)
+		if err != nil {
+			return nil, nil, err
+		}
+		if w := listing.Warning(); w != "" {
+			warnings = append(warnings, sourceName(s)+": "+w)
+		}
+		selected, err := RepoFilter{Include: s.Match, Exclude: s.Exclude}.Apply(listing.Repos)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, r := range selected {
+			key := strings.ToLower(r.Owner + "/" + r.Name)
+			if _, ok := index[key]; ok {
+				continue // selected by an earlier source
+			}
+			rel, err := layout.Path(r)
+			if err != nil {
+				return nil, nil, err
+			}
+			index[key] = len(repos)
+			repos = append(repos, WorkspaceRepo{
+				Repo:   r,
+				Path:   filepath.Join(filepath.FromSlash(s.Path), rel),
+				Branch: s.Branch,
+				Hooks:  append(append([]string{}, m.Hooks...), s.Hooks...),
+			})
+		}
+	}
+
+	for _, mr := range m.Repos {
+		r := manifestRepo(mr)
+		key := strings.ToLower(r.Owner + "/" + r.Name)
+		if r.Owner == "" {
+			key = r.SSHUrl // not a GitHub-style URL; only the same URL matches
+		}
+		i, ok := index[key]
+		if !ok {
+			rel := filepath.FromSlash(mr.Path)
+			if rel == "" {
+				var err error
+				if rel, err = layout.Path(r); err != nil {
+					return nil, nil, err
+				}
+			}
+			i = len(repos)
+			index[key] = i
+			repos = append(repos, WorkspaceRepo{Repo: r, Path: rel, Hooks: append([]string{}, m.Hooks...)})
+		}
+		w := &repos[i]
+		if mr.URL != "" {
+			w.Repo.SSHUrl = mr.URL
+		}
+		if mr.Path != "" {
+			w.Path = filepath.FromSlash(mr.Path)
+		}
+		if mr.Branch != "" {
+			w.Branch = mr.Branch
+		}
+		w.Remotes = mr.Remotes
+		w.Hooks = append(w.Hooks, mr.Hooks...)
+	}
+
+	paths := make(map[string]string, len(repos))
+	for _, w := range repos {
+		name := w.Repo.Owner + "/" + w.Repo.Name
+		p := strings.ToLower(filepath.Clean(w.Path))
+		if other, ok := paths[p]; ok {
+			return nil, nil, fmt.Errorf("%s and %s both check out into %s", other, name, w.Path)
+		}
+		paths[p] = name
+	}
+	return repos, warnings, nil
+}
+
+// listSource lists the repositories a manifest source selects from.
+func listSource(s manifest.Source, limit int) (Listing, error) {
+	switch {
+	case s.Self:
+		return ListSelfRepos(limit)
+	case s.User != "":
+		return ListPublicRepos(s.User, limit)
+	default:
+		return ListOrgRepos(s.Org, limit)
+	}
+}
+
+// sourceName describes a manifest source in messages, e.g. "org my-org".
+func sourceName(s manifest.Source) string {
+	switch {
+	case s.Self:
+		return "self"
+	case s.User != "":
+		return "user " + s.User
+	default:
+		return "org " + s.Org
+	}
+}
+
+// manifestRepo returns the repository a manifest repo refers to. Without a
+// URL it is cloned from GitHub over SSH; without a name, its owner and name
+// come from the URL, or its name from the last path element of a URL that
+// is not a remote URL, such as a local path.
+func manifestRepo(mr manifest.Repo) github.Repo {
+	owner, name, _ := strings.Cut(mr.Name, "/")
+	url := mr.URL
+	switch {
+	case url == "":
+		url = fmt.Sprintf("git@github.com:%s/%s.git", owner, name)
+	case mr.Name == "":
+		if u, err := remoteurl.Parse(url); err == nil {
+			owner, name = u.Owner, u.Repo
+		} else {
+			// A local path or another URL git understands.
+			name = strings.TrimSuffix(path.Base(filepath.ToSlash(url)), ".git")
+		}
+	}
+	return github.Repo{Owner: owner, Name: name, SSHUrl: url}
+}
+
+// ApplyOptions controls ApplyWorkspace.
+type ApplyOptions struct {
+	// Root is the directory the workspace lives in.
+	Root string
+	// Jobs is the number of repositories processed at once.
+	Jobs int
+	// OnConflict applies to checkouts that already exist. ConflictReclone
+	// is not allowed, and is treated as ConflictFail.
+	OnConflict ConflictPolicy
+	// Hooks run after cloning, before the manifest's own hooks.
+	Hooks []config.Hook
+	// OnResult, if set, is called as each repository finishes.
+	OnResult func(Result)
+}
+
+// ApplyWorkspace makes the checkouts under opts.Root match repos: missing
+// repositories are cloned and their hooks run, existing checkouts are
+// handled according to opts.OnConflict, and in both cases the manifest's
+// branch is checked out and its extra remotes are added or corrected.
+// A checkout with uncommitted changes is never switched to another branch;
+// it is reported as StatusAttention instead. Nothing is ever deleted.
+//
+// Statuses are those of CloneRepos plus StatusAttention and
+// StatusHookFailed. Result names are paths relative to opts.Root.
+func ApplyWorkspace(ctx context.Context, repos []WorkspaceRepo, opts ApplyOptions) []Result {
+	policy := opts.OnConflict
+	switch policy {
+	case "":
+		policy = ConflictSkip
+	case ConflictReclone:
+		policy = ConflictFail
+	}
+	tasks := make([]Task, len(repos))
+	for i, w := range repos {
+		w := w
+		dest := filepath.Join(opts.Root, w.Path)
+		tasks[i] = Task{
+			Name:   filepath.ToSlash(w.Path),
+			Dir:    dest,
+			Remote: w.Repo.SSHUrl,
+			Run: func(ctx context.Context) Outcome {
+				return applyRepo(ctx, w, dest, policy, opts.Hooks)
+			},
+		}
+	}
+	ex := NewExecutor(opts.Jobs)
+	ex.OnResult = opts.OnResult
+	return ex.Run(ctx, tasks)
+}
+
+// applyRepo converges the checkout of w at dest. After a clone, the
+// matching hooks run first, then those of the manifest.
+func applyRepo(ctx context.Context, w WorkspaceRepo, dest string, policy ConflictPolicy, hooks []config.Hook) Outcome {
+	res := cloneOrUpdate(ctx, w.Repo, dest, policy)
+	if res.Err != nil {
+		return res
+	}
+	cloned := res.Status == StatusCloned
+	var out strings.Builder
+	out.WriteString(res.Output)
+
+	if w.Branch != "" {
+		switched, reason, err := checkoutBranch(ctx, dest, w.Branch, cloned)
+		switch {
+		case err != nil:
+			return Outcome{Output: out.String(), Err: err}
+		case reason != "":
+			return Outcome{Status: StatusAttention, Output: out.String() + reason + "\n"}
+		case switched:
+			fmt.Fprintf(&out, "checked out %s\n", w.Branch)
+			if !cloned {
+				res.Status = StatusUpdated
+			}
+		}
+	}
+
+	names := make([]string, 0, len(w.Remotes))
+	for name := range w.Remotes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		msg, err := ensureRemote(ctx, dest, name, w.Remotes[name])
+		if err != nil {
+			return Outcome{Output: out.String(), Err: err}
+		}
+		if msg != "" {
+			out.WriteString(msg)
+			if !cloned {
+				res.Status = StatusUpdated
+			}
+		}
+	}
+
+	if cloned {
+		cmds := append(hookCommands(ctx, hooks, w.Repo, dest), w.Hooks...)
+		hookOut, err := runHooks(ctx, dest, cmds)
+		out.WriteString(hookOut)
+		if err != nil {
+			return Outcome{Status: StatusHookFailed, Output: out.String(), Err: err}
+		}
+	}
+	return Outcome{Status: res.Status, Output: out.String()}
+}
+
+// checkoutBranch checks out branch in the repository at dir unless it is
+// already current. fresh says dir was just cloned (or, in a dry run, would
+// have been). If the working tree has uncommitted changes, nothing is done
+// and reason says why.
+func checkoutBranch(ctx context.Context, dir, branch string, fresh bool) (switched bool, reason string, err error) {
+	if !(fresh && git.IsDryRun(ctx)) {
+		st, err := git.GetStatus(ctx, dir)
+		if err != nil {
+			return false, "", err
+		}
+		if !st.Detached && st.Branch == branch {
+			return false, "", nil
+		}
+		if st.Dirty {
+			return false, fmt.Sprintf("on %s, not %s, with uncommitted changes", st.Branch, branch), nil
+		}
+	}
+	if out, err := git.Mutate(ctx, dir, "checkout", "--quiet", branch); err != nil {
+		return false, "", fmt.Errorf("failed to check out %s: %w: %s", branch, err, strings.TrimSpace(out))
+	}
+	return true, "", nil
+}
+
+// ensureRemote makes the remote called name of the repository at dir point
+// at url, and describes what it changed.
+func ensureRemote(ctx context.Context, dir, name, url string) (string, error) {
+	current, err := getRemoteURL(dir, name)
+	switch {
+	case err != nil:
+		if out, err := git.Mutate(ctx, dir, "remote", "add", name, url); err != nil {
+			return "", fmt.Errorf("failed to add remote %s: %w: %s", name, err, strings.TrimSpace(out))
+		}
+		return fmt.Sprintf("added remote %s\n", name), nil
+	case current != url:
+		if out, err := git.Mutate(ctx, dir, "remote", "set-url", name, url); err != nil {
+			return "", fmt.Errorf("failed to set remote %s: %w: %s", name, err, strings.TrimSpace(out))
+		}
+		return fmt.Sprintf("%s:\n- %s\n+ %s\n", name, current, url), nil
+	}
+	return "", nil
+}
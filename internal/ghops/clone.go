@@ -0,0 +1,230 @@
+package ghops
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sanurb/ghpm/internal/config"
+	"github.com/sanurb/ghpm/internal/git"
+	"github.com/sanurb/ghpm/internal/github"
+	"github.com/sanurb/ghpm/internal/remoteurl"
+)
+
+// ConflictPolicy says what a batch clone does when a repository's
+// destination directory already exists.
+type ConflictPolicy string
+
+const (
+	ConflictSkip    ConflictPolicy = "skip"    // leave the existing checkout alone
+	ConflictPull    ConflictPolicy = "pull"    // fast-forward it with "git pull --ff-only"
+	ConflictFetch   ConflictPolicy = "fetch"   // update its remote-tracking branches only
+	ConflictReclone ConflictPolicy = "reclone" // delete it and clone again, unless it has unpushed work
+	ConflictFail    ConflictPolicy = "fail"    // report it as a conflict
+)
+
+// ConflictPolicies lists the valid conflict policies.
+var ConflictPolicies = []ConflictPolicy{ConflictSkip, ConflictPull, ConflictFetch, ConflictReclone, ConflictFail}
+
+// ParseConflictPolicy validates a policy name; "" means ConflictSkip.
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	if s == "" {
+		return ConflictSkip, nil
+	}
+	for _, p := range ConflictPolicies {
+		if string(p) == s {
+			return p, nil
+		}
+	}
+	names := make([]string, len(ConflictPolicies))
+	for i, p := range ConflictPolicies {
+		names[i] = string(p)
+	}
+	return "", fmt.Errorf("invalid conflict policy %q (want one of %s)", s, strings.Join(names, ", "))
+}
+
+// Statuses reported by CloneRepos.
+const (
+	StatusCloned   = "cloned"
+	StatusUpdated  = "updated"
+	StatusSkipped  = "skipped"
+	StatusConflict = "conflict"
+)
+
+// CloneOptions controls CloneRepos.
+type CloneOptions struct {
+	// Dest is the directory repositories are cloned under; empty means
+	// the current directory.
+	Dest string
+	// Jobs is the number of clones run at once.
+	Jobs int
+	// Layout places each repository under Dest; nil means DefaultLayout.
+	Layout *Layout
+	// OnConflict applies when a destination directory already exists.
+	OnConflict ConflictPolicy
+	// Hooks run in each repository that is cloned, when it matches. Their
+	// output is added to the Result's, and a failing hook turns a
+	// StatusCloned into StatusHookFailed.
+	Hooks []config.Hook
+	// OnResult, if set, is called as each repository finishes.
+	OnResult func(Result)
+}
+
+// CloneRepos clones every repo into the directory opts.Layout gives it
+// under opts.Dest. Existing checkouts of the same repository are handled
+// according to opts.OnConflict; anything else in the way is reported as a
+// conflict. Each Result's Status is one of StatusCloned, StatusUpdated,
+// StatusSkipped, StatusConflict, StatusHookFailed or StatusFailed, and
+// results are in the same order as repos. Result names are the layout
+// paths.
+func CloneRepos(ctx context.Context, repos []github.Repo, opts CloneOptions) []Result {
+	policy := opts.OnConflict
+	if policy == "" {
+		policy = ConflictSkip
+	}
+	tasks := make([]Task, 0, len(repos))
+	for _, r := range repos {
+		r := r
+		rel, err := opts.Layout.Path(r)
+		if err != nil {
+			tasks = append(tasks, Task{
+				Name:   r.Name,
+				Remote: r.SSHUrl,
+				Run: func(ctx context.Context) Outcome {
+					return Outcome{Err: err}
+				},
+			})
+			continue
+		}
+		dest := filepath.Join(opts.Dest, rel)
+		tasks = append(tasks, Task{
+			Name:   filepath.ToSlash(rel),
+			Dir:    dest,
+			Remote: r.SSHUrl,
+			Run: func(ctx context.Context) Outcome {
+				return afterClone(ctx, opts.Hooks, r, dest, cloneOrUpdate(ctx, r, dest, policy))
+			},
+		})
+	}
+	ex := NewExecutor(opts.Jobs)
+	ex.OnResult = opts.OnResult
+	return ex.Run(ctx, tasks)
+}
+
+// cloneOrUpdate clones r into dest, or applies policy if dest exists.
+func cloneOrUpdate(ctx context.Context, r github.Repo, dest string, policy ConflictPolicy) Outcome {
+	exists, empty, err := dirState(dest)
+	if err != nil {
+		return Outcome{Err: err}
+	}
+	if !exists || empty {
+		return clone(ctx, r, dest)
+	}
+
+	if !git.IsRepo(dest) {
+		return Outcome{Status: StatusConflict, Err: fmt.Errorf("%s exists and is not a git repository", dest)}
+	}
+	origin, err := getRemoteURL(dest, "origin")
+	if err != nil {
+		return Outcome{Status: StatusConflict, Err: fmt.Errorf("%s exists but has no origin remote", dest)}
+	}
+	if origin != r.SSHUrl && !sameGitHubRepo(origin, r) {
+		return Outcome{Status: StatusConflict, Err: fmt.Errorf("%s is a checkout of %s, not %s/%s", dest, origin, r.Owner, r.Name)}
+	}
+
+	switch policy {
+	case ConflictSkip:
+		return Outcome{Status: StatusSkipped, Output: "already cloned\n"}
+	case ConflictPull:
+		out, err := git.Mutate(ctx, dest, "pull", "--ff-only")
+		if err != nil {
+			return Outcome{Output: out, Err: err}
+		}
+		return Outcome{Status: StatusUpdated, Output: out}
+	case ConflictFetch:
+		out, err := git.Mutate(ctx, dest, "fetch", "--all", "--prune")
+		if err != nil {
+			return Outcome{Output: out, Err: err}
+		}
+		return Outcome{Status: StatusUpdated, Output: out}
+	case ConflictReclone:
+		st, err := git.GetStatus(ctx, dest)
+		if err != nil {
+			return Outcome{Err: err}
+		}
+		if reason := unsavedWork(st); reason != "" {
+			return Outcome{Status: StatusConflict, Err: fmt.Errorf("not recloning %s: %s", dest, reason)}
+		}
+		if p := git.PlanFrom(ctx); p != nil {
+			p.Add(".", "rm", "-rf", dest)
+		} else if err := os.RemoveAll(dest); err != nil {
+			return Outcome{Err: fmt.Errorf("failed to remove %s: %w", dest, err)}
+		}
+		return clone(ctx, r, dest)
+	default:
+		return Outcome{Status: StatusConflict, Err: fmt.Errorf("%s already exists", dest)}
+	}
+}
+
+// unsavedWork describes what deleting a checkout with status st would
+// lose, or returns "" if everything in it is on its upstream.
+func unsavedWork(st git.Status) string {
+	var reasons []string
+	switch {
+	case st.Detached:
+		reasons = append(reasons, "HEAD is detached")
+	case st.Upstream == "":
+		reasons = append(reasons, st.Branch+" has no upstream")
+	case st.Ahead > 0:
+		reasons = append(reasons, fmt.Sprintf("%d unpushed commits", st.Ahead))
+	}
+	if st.Dirty {
+		reasons = append(reasons, "uncommitted changes")
+	}
+	if st.Untracked > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d untracked files", st.Untracked))
+	}
+	if st.Stashes > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d stashes", st.Stashes))
+	}
+	return strings.Join(reasons, ", ")
+}
+
+func clone(ctx context.Context, r github.Repo, dest string) Outcome {
+	if err := CloneRepo(ctx, r.SSHUrl, dest); err != nil {
+		return Outcome{Err: err}
+	}
+	return Outcome{Status: StatusCloned}
+}
+
+// dirState reports whether path exists and, if it is a directory, whether
+// it is empty. A path that exists but is not a directory counts as
+// non-empty.
+func dirState(path string) (exists, empty bool, err error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, err
+	}
+	if !info.IsDir() {
+		return true, false, nil
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return true, false, err
+	}
+	return true, len(entries) == 0, nil
+}
+
+// sameGitHubRepo reports whether the remote URL points at r.
+func sameGitHubRepo(remote string, r github.Repo) bool {
+	u, err := remoteurl.Parse(remote)
+	if err != nil {
+		return false
+	}
+	return (r.Owner == "" || strings.EqualFold(u.Owner, r.Owner)) && strings.EqualFold(u.Repo, r.Name)
+}
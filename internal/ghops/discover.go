@@ -0,0 +1,309 @@
+package ghops
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// IgnoreFile is the file, at the root of a search, whose patterns are
+// added to DiscoverOptions.Exclude. It holds one pattern per line; blank
+// lines and lines starting with "#" are ignored.
+const IgnoreFile = ".ghpmignore"
+
+// RepoKind classifies a repository found by discovery.
+type RepoKind string
+
+const (
+	KindNormal    RepoKind = "normal"    // a work tree with its own .git directory
+	KindWorktree  RepoKind = "worktree"  // a linked work tree made by "git worktree add"
+	KindSubmodule RepoKind = "submodule" // a submodule checked out inside another repository
+	KindBare      RepoKind = "bare"      // a repository without a work tree, e.g. a mirror
+)
+
+// RepoKinds lists the valid kinds.
+var RepoKinds = []RepoKind{KindNormal, KindWorktree, KindSubmodule, KindBare}
+
+// DefaultKinds are the kinds discovery selects when none are given.
+// Submodules are left to their parent repository, and bare repositories
+// have no work tree to run commands in.
+var DefaultKinds = []RepoKind{KindNormal, KindWorktree}
+
+// ParseRepoKinds validates kind names; "all" stands for every kind.
+func ParseRepoKinds(names []string) ([]RepoKind, error) {
+	var kinds []RepoKind
+	for _, n := range names {
+		if n == "all" {
+			return RepoKinds, nil
+		}
+		k := RepoKind(strings.ToLower(n))
+		if !slices.Contains(RepoKinds, k) {
+			valid := make([]string, len(RepoKinds))
+			for i, k := range RepoKinds {
+				valid[i] = string(k)
+			}
+			return nil, fmt.Errorf("invalid repository kind %q (want one of %s, or all)", n, strings.Join(valid, ", "))
+		}
+		kinds = append(kinds, k)
+	}
+	return kinds, nil
+}
+
+// DiscoverOptions selects the repositories batch operations work on.
+//
+// Patterns are globs matched against a directory's path relative to the
+// root, with "/" separators, and against the paths of its parents. A
+// pattern without a "/" matches a directory name at any depth instead, so
+// "node_modules" skips every node_modules directory and "svc-*" includes
+// every repository under svc-api. A leading or trailing "/" is ignored.
+// The root itself has the path ".", which "*" and "." match.
+type DiscoverOptions struct {
+	// MaxDepth stops the search that many directories below the root;
+	// 0 means no limit.
+	MaxDepth int
+	// Include keeps only repositories matching at least one pattern.
+	// Empty keeps all.
+	Include []string
+	// Exclude skips directories matching any pattern, along with
+	// everything below them.
+	Exclude []string
+	// Kinds keeps only repositories of these kinds; empty means
+	// DefaultKinds. It does not change where the search looks.
+	Kinds []RepoKind
+	// Warn, if set, is told about directories that could not be read.
+	// They are skipped either way.
+	Warn func(msg string)
+}
+
+// ParseFilters splits --filter values into include and exclude patterns:
+// a pattern starting with "!" excludes, any other includes.
+func ParseFilters(filters []string) (include, exclude []string) {
+	for _, f := range filters {
+		if p, ok := strings.CutPrefix(f, "!"); ok {
+			exclude = append(exclude, p)
+		} else {
+			include = append(include, f)
+		}
+	}
+	return include, exclude
+}
+
+// discoverLocalRepos returns the directories of the repositories under
+// root that opts selects, in walk order.
+func discoverLocalRepos(root string, opts DiscoverOptions) ([]string, error) {
+	found, err := discover(root, opts)
+	if err != nil {
+		return nil, err
+	}
+	dirs := make([]string, len(found))
+	for i, f := range found {
+		dirs[i] = f.Dir
+	}
+	return dirs, nil
+}
+
+// foundRepo is a repository found by discover.
+type foundRepo struct {
+	Dir  string
+	Kind RepoKind
+}
+
+// discover returns the repositories under root that opts selects, in walk
+// order. Repositories are found at any depth up to opts.MaxDepth, so those
+// cloned with any Layout are picked up, and the walk continues inside work
+// trees to find nested repositories, worktrees and submodules; Exclude
+// patterns such as "node_modules" keep it from entering large directories.
+// It skips .git directories and the insides of bare repositories.
+// Directories that cannot be read are reported to opts.Warn and skipped;
+// only an unreadable root is an error.
+func discover(root string, opts DiscoverOptions) ([]foundRepo, error) {
+	opts, err := opts.forRoot(root)
+	if err != nil {
+		return nil, err
+	}
+	kinds := opts.Kinds
+	if len(kinds) == 0 {
+		kinds = DefaultKinds
+	}
+
+	var repos []foundRepo
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == root {
+				return err
+			}
+			if opts.Warn != nil {
+				opts.Warn(fmt.Sprintf("skipping %s: %v", p, err))
+			}
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if d.Name() == ".git" && p != root {
+			return fs.SkipDir
+		}
+		rel := "."
+		if p != root {
+			rel = filepath.ToSlash(relPath(root, p))
+			if matchPath(opts.Exclude, rel) {
+				return fs.SkipDir
+			}
+			if opts.MaxDepth > 0 && strings.Count(rel, "/")+1 > opts.MaxDepth {
+				return fs.SkipDir
+			}
+		}
+		kind, ok := repoKind(p)
+		if !ok {
+			return nil
+		}
+		if slices.Contains(kinds, kind) && opts.selects(rel) {
+			repos = append(repos, foundRepo{Dir: p, Kind: kind})
+		}
+		if kind == KindBare {
+			return fs.SkipDir // its insides are git's own
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return repos, nil
+}
+
+// repoKind reports whether dir is a repository and, if so, what kind. A
+// .git directory makes a normal repository. A .git file makes a worktree
+// when it points at a linked worktree's git directory, which holds a
+// commondir file, and a submodule when it points into another
+// repository's .git/modules. A directory holding HEAD, objects and refs is
+// bare.
+func repoKind(dir string) (RepoKind, bool) {
+	gitPath := filepath.Join(dir, ".git")
+	if info, err := os.Stat(gitPath); err == nil {
+		if info.IsDir() {
+			return KindNormal, true
+		}
+		data, err := os.ReadFile(gitPath)
+		if err != nil {
+			return "", false
+		}
+		gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+		if !ok {
+			return "", false
+		}
+		gitDir = strings.TrimSpace(gitDir)
+		if !filepath.IsAbs(gitDir) {
+			gitDir = filepath.Join(dir, gitDir)
+		}
+		switch {
+		case isFile(filepath.Join(gitDir, "commondir")):
+			return KindWorktree, true // only linked worktrees share another's objects
+		case strings.Contains(filepath.ToSlash(gitDir), "/.git/modules/"):
+			return KindSubmodule, true
+		default:
+			return KindNormal, true // e.g. git init --separate-git-dir
+		}
+	}
+	for _, name := range []string{"objects", "refs"} {
+		if info, err := os.Stat(filepath.Join(dir, name)); err != nil || !info.IsDir() {
+			return "", false
+		}
+	}
+	if info, err := os.Stat(filepath.Join(dir, "HEAD")); err != nil || info.IsDir() {
+		return "", false
+	}
+	return KindBare, true
+}
+
+// isFile reports whether name exists and is not a directory.
+func isFile(name string) bool {
+	info, err := os.Stat(name)
+	return err == nil && !info.IsDir()
+}
+
+// forRoot returns opts with the patterns of root's IgnoreFile added to
+// Exclude, after checking that every pattern is valid.
+func (opts DiscoverOptions) forRoot(root string) (DiscoverOptions, error) {
+	ignored, err := readIgnoreFile(filepath.Join(root, IgnoreFile))
+	if err != nil {
+		return opts, err
+	}
+	opts.Exclude = append(ignored, opts.Exclude...)
+	for _, p := range append(append([]string{}, opts.Include...), opts.Exclude...) {
+		if _, err := path.Match(cleanPattern(p), ""); err != nil {
+			return opts, fmt.Errorf("invalid filter pattern %q: %w", p, err)
+		}
+	}
+	return opts, nil
+}
+
+// selects reports whether a repository at rel, a path relative to the
+// root, passes the Include and Exclude patterns of opts, as returned by
+// forRoot.
+func (opts DiscoverOptions) selects(rel string) bool {
+	rel = filepath.ToSlash(rel)
+	return (len(opts.Include) == 0 || matchPath(opts.Include, rel)) && !matchPath(opts.Exclude, rel)
+}
+
+// matchPath reports whether rel, a slash-separated path relative to the
+// root, or one of its parents matches any of the patterns. Patterns are
+// expected to have been validated already.
+func matchPath(patterns []string, rel string) bool {
+	for dir := rel; ; {
+		for _, p := range patterns {
+			p = cleanPattern(p)
+			target := dir
+			if !strings.Contains(p, "/") {
+				target = path.Base(dir)
+			}
+			if ok, _ := path.Match(p, target); ok {
+				return true
+			}
+		}
+		parent := path.Dir(dir)
+		if parent == "." || parent == "/" || parent == dir {
+			return false
+		}
+		dir = parent
+	}
+}
+
+// cleanPattern drops the leading and trailing slashes a pattern may be
+// written with.
+func cleanPattern(p string) string {
+	return strings.Trim(p, "/")
+}
+
+// readIgnoreFile returns the patterns in the ignore file at name, or none
+// if there is no such file.
+func readIgnoreFile(name string) ([]string, error) {
+	f, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var patterns []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return patterns, nil
+}
@@ -0,0 +1,311 @@
+package ghops
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// runGit runs git in dir and fails the test if it does not succeed.
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %s in %s: %v\n%s", strings.Join(args, " "), dir, err, out)
+	}
+}
+
+// initRepo creates a repository with one commit at dir.
+func initRepo(t *testing.T, dir string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, dir, "init", "--quiet")
+	runGit(t, dir, "commit", "--quiet", "--allow-empty", "-m", "initial")
+}
+
+// discoveryTree builds, under a temporary root:
+//
+//	a/                   normal
+//	svc-x/one/           normal
+//	svc-y/               normal
+//	deep/1/2/3/r/        normal
+//	node_modules/pkg/    normal
+//	outer/               normal
+//	outer/libs/inner/    normal, nested in outer
+//	outer/.wt/feat/      worktree of outer
+//	outer/sub/           submodule of outer
+//	modules/proj/        normal
+//	wt2/                 worktree of modules/proj
+//	mirror.git/          bare
+//	notes/               not a repository
+func discoveryTree(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	root := t.TempDir()
+	for _, dir := range []string{"a", "svc-x/one", "svc-y", "deep/1/2/3/r", "node_modules/pkg", "outer", "outer/libs/inner", "modules/proj"} {
+		initRepo(t, filepath.Join(root, dir))
+	}
+	outer := filepath.Join(root, "outer")
+	runGit(t, outer, "worktree", "add", "--quiet", ".wt/feat")
+	runGit(t, outer, "-c", "protocol.file.allow=always", "submodule", "--quiet", "add", filepath.Join(root, "a"), "sub")
+	runGit(t, filepath.Join(root, "modules/proj"), "worktree", "add", "--quiet", "../../wt2")
+	runGit(t, root, "clone", "--quiet", "--bare", filepath.Join(root, "a"), "mirror.git")
+	if err := os.MkdirAll(filepath.Join(root, "notes"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+// found lists what discover returns as "path:kind", relative to root, in
+// walk order.
+func found(t *testing.T, root string, opts DiscoverOptions) []string {
+	t.Helper()
+	repos, err := discover(root, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range repos {
+		got = append(got, filepath.ToSlash(relPath(root, r.Dir))+":"+string(r.Kind))
+	}
+	return got
+}
+
+func TestDiscover(t *testing.T) {
+	root := discoveryTree(t)
+	tests := []struct {
+		name string
+		opts DiscoverOptions
+		want []string
+	}{
+		{
+			name: "default kinds",
+			want: []string{
+				"a:normal", "deep/1/2/3/r:normal", "modules/proj:normal", "node_modules/pkg:normal",
+				"outer:normal", "outer/.wt/feat:worktree", "outer/libs/inner:normal",
+				"svc-x/one:normal", "svc-y:normal", "wt2:worktree",
+			},
+		},
+		{
+			name: "all kinds",
+			opts: DiscoverOptions{Kinds: RepoKinds},
+			want: []string{
+				"a:normal", "deep/1/2/3/r:normal", "mirror.git:bare", "modules/proj:normal", "node_modules/pkg:normal",
+				"outer:normal", "outer/.wt/feat:worktree", "outer/libs/inner:normal", "outer/sub:submodule",
+				"svc-x/one:normal", "svc-y:normal", "wt2:worktree",
+			},
+		},
+		{
+			name: "normal only still finds nested repos",
+			opts: DiscoverOptions{Kinds: []RepoKind{KindNormal}, Include: []string{"outer"}},
+			want: []string{"outer:normal", "outer/libs/inner:normal"},
+		},
+		{
+			name: "submodules and bare",
+			opts: DiscoverOptions{Kinds: []RepoKind{KindSubmodule, KindBare}},
+			want: []string{"mirror.git:bare", "outer/sub:submodule"},
+		},
+		{
+			name: "bare-name include matches a parent at any depth",
+			opts: DiscoverOptions{Include: []string{"svc-*"}},
+			want: []string{"svc-x/one:normal", "svc-y:normal"},
+		},
+		{
+			name: "path include",
+			opts: DiscoverOptions{Include: []string{"svc-x/*"}},
+			want: []string{"svc-x/one:normal"},
+		},
+		{
+			name: "slashes around a pattern are ignored",
+			opts: DiscoverOptions{Include: []string{"/svc-y/"}},
+			want: []string{"svc-y:normal"},
+		},
+		{
+			name: "bare-name exclude skips the whole directory",
+			opts: DiscoverOptions{Exclude: []string{"node_modules", "outer", "deep"}},
+			want: []string{"a:normal", "modules/proj:normal", "svc-x/one:normal", "svc-y:normal", "wt2:worktree"},
+		},
+		{
+			name: "path exclude",
+			opts: DiscoverOptions{Exclude: []string{"outer/libs"}, Include: []string{"outer"}},
+			want: []string{"outer:normal", "outer/.wt/feat:worktree"},
+		},
+		{
+			name: "include and exclude",
+			opts: DiscoverOptions{Include: []string{"svc-*"}, Exclude: []string{"one"}},
+			want: []string{"svc-y:normal"},
+		},
+		{
+			name: "max depth",
+			opts: DiscoverOptions{MaxDepth: 1},
+			want: []string{"a:normal", "outer:normal", "svc-y:normal", "wt2:worktree"},
+		},
+		{
+			name: "max depth counts nested repos",
+			opts: DiscoverOptions{MaxDepth: 3, Include: []string{"outer"}},
+			want: []string{"outer:normal", "outer/.wt/feat:worktree", "outer/libs/inner:normal"},
+		},
+		{
+			name: "no match",
+			opts: DiscoverOptions{Include: []string{"nothing-*"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := found(t, root, tt.opts)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got  %v\nwant %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiscoverRootRepo(t *testing.T) {
+	root := filepath.Join(discoveryTree(t), "outer")
+	tests := []struct {
+		include []string
+		want    []string
+	}{
+		{nil, []string{".:normal", ".wt/feat:worktree", "libs/inner:normal"}},
+		{[]string{"*"}, []string{".:normal", ".wt/feat:worktree", "libs/inner:normal"}},
+		{[]string{"."}, []string{".:normal"}},
+		{[]string{"libs"}, []string{"libs/inner:normal"}},
+	}
+	for _, tt := range tests {
+		got := found(t, root, DiscoverOptions{Include: tt.include})
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("include %q: got %v, want %v", tt.include, got, tt.want)
+		}
+	}
+}
+
+func TestDiscoverIgnoreFile(t *testing.T) {
+	root := discoveryTree(t)
+	ignore := "# generated\n\nnode_modules\n/deep/\n  outer  \n"
+	if err := os.WriteFile(filepath.Join(root, IgnoreFile), []byte(ignore), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got := found(t, root, DiscoverOptions{Exclude: []string{"modules"}})
+	want := []string{"a:normal", "svc-x/one:normal", "svc-y:normal", "wt2:worktree"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestDiscoverInvalidPattern(t *testing.T) {
+	root := t.TempDir()
+	if _, err := discover(root, DiscoverOptions{Include: []string{"[a-"}}); err == nil {
+		t.Error("an invalid include pattern was accepted")
+	}
+	if err := os.WriteFile(filepath.Join(root, IgnoreFile), []byte("[\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := discover(root, DiscoverOptions{}); err == nil {
+		t.Error("an invalid ignore file pattern was accepted")
+	}
+}
+
+func TestDiscoverUnreadable(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can read any directory")
+	}
+	root := discoveryTree(t)
+	locked := filepath.Join(root, "locked")
+	initRepo(t, filepath.Join(locked, "r"))
+	if err := os.Chmod(locked, 0); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(locked, 0o755) })
+
+	var warnings []string
+	got := found(t, root, DiscoverOptions{
+		Include: []string{"a", "locked"},
+		Warn:    func(msg string) { warnings = append(warnings, msg) },
+	})
+	if want := []string{"a:normal"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], locked) {
+		t.Errorf("warnings = %q, want one about %s", warnings, locked)
+	}
+
+	if _, err := discover(locked, DiscoverOptions{}); err == nil {
+		t.Error("an unreadable root was not an error")
+	}
+}
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern string
+		rel     string
+		want    bool
+	}{
+		{"svc-*", "svc-api", true},
+		{"svc-*", "group/svc-api", true},
+		{"svc-*", "svc-api/nested", true},
+		{"svc-*", "api", false},
+		{"node_modules", "web/node_modules/pkg", true},
+		{"group/*", "group/repo", true},
+		{"group/*", "group/repo/nested", true},
+		{"group/*", "other/group/repo", false},
+		{"/group/", "group/repo", true},
+		{"a/b", "a/b", true},
+		{"a/b", "x/a/b", false},
+		{"*", ".", true},
+		{".", ".", true},
+		{"repo", ".", false},
+		{".", "repo", false},
+	}
+	for _, tt := range tests {
+		if got := matchPath([]string{tt.pattern}, tt.rel); got != tt.want {
+			t.Errorf("matchPath(%q, %q) = %v, want %v", tt.pattern, tt.rel, got, tt.want)
+		}
+	}
+	if matchPath(nil, "repo") {
+		t.Error("no patterns matched")
+	}
+}
+
+func TestParseRepoKinds(t *testing.T) {
+	tests := []struct {
+		in   []string
+		want []RepoKind
+		ok   bool
+	}{
+		{nil, nil, true},
+		{[]string{"normal", "Bare"}, []RepoKind{KindNormal, KindBare}, true},
+		{[]string{"submodule", "all"}, RepoKinds, true},
+		{[]string{"nested"}, nil, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseRepoKinds(tt.in)
+		if (err == nil) != tt.ok || !slices.Equal(got, tt.want) {
+			t.Errorf("ParseRepoKinds(%q) = %v, %v; want %v, ok=%v", tt.in, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseFilters(t *testing.T) {
+	include, exclude := ParseFilters([]string{"svc-*", "!node_modules", "group/*", "!/archive/"})
+	if want := []string{"svc-*", "group/*"}; !slices.Equal(include, want) {
+		t.Errorf("include = %q, want %q", include, want)
+	}
+	if want := []string{"node_modules", "/archive/"}; !slices.Equal(exclude, want) {
+		t.Errorf("exclude = %q, want %q", exclude, want)
+	}
+}
@@ -0,0 +1,229 @@
+package ghops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sanurb/ghpm/internal/config"
+)
+
+// Task is a unit of work run by an Executor, usually against a single
+// repository.
+type Task struct {
+	Name string
+	// Dir is the repository directory the task works in, if any.
+	Dir string
+	// Remote is the URL of the repository the task works on, if known.
+	Remote string
+	Run    func(ctx context.Context) Outcome
+}
+
+// Outcome is what a Task's Run reports.
+type Outcome struct {
+	// Status is a short word describing what happened, e.g. "cloned" or
+	// "skipped". When empty, the Result's status is derived from Err.
+	Status string
+	Output string // whatever output the task captured
+	// Stderr is the task's standard error, when it captured it apart
+	// from Output.
+	Stderr string
+	Err    error
+}
+
+// Statuses filled in by the Executor when a task does not report one.
+const (
+	StatusOK       = "ok"
+	StatusFailed   = "failed"
+	StatusCanceled = "canceled"
+)
+
+// Result is the outcome of running a single Task.
+type Result struct {
+	Index    int // position of the task in the slice passed to Run
+	Name     string
+	Dir      string
+	Remote   string
+	Status   string
+	Output   string
+	Stderr   string
+	Err      error
+	Duration time.Duration
+}
+
+// ExitCode returns the exit status of the process behind r: 0 on success,
+// the process's exit code if it ran and failed, and -1 if it never ran or
+// failed for another reason.
+func (r Result) ExitCode() int {
+	if r.Err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(r.Err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// ErrStopped is the error of tasks an Executor with FailFast set never
+// started because an earlier task failed.
+var ErrStopped = errors.New("not started: an earlier repository failed")
+
+// NotStarted reports whether the task was never started because the run
+// was canceled or stopped first.
+func (r Result) NotStarted() bool {
+	return r.Duration == 0 && (errors.Is(r.Err, ErrStopped) || errors.Is(r.Err, context.Canceled) || errors.Is(r.Err, context.DeadlineExceeded))
+}
+
+// Summary counts results by status, e.g. "3 cloned, 1 failed, 5 skipped".
+// Statuses are listed alphabetically.
+func Summary(results []Result) string {
+	counts := map[string]int{}
+	for _, r := range results {
+		counts[r.Status]++
+	}
+	statuses := make([]string, 0, len(counts))
+	for s := range counts {
+		statuses = append(statuses, s)
+	}
+	sort.Strings(statuses)
+	parts := make([]string, len(statuses))
+	for i, s := range statuses {
+		parts[i] = fmt.Sprintf("%d %s", counts[s], s)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Executor runs tasks concurrently with at most Jobs of them in flight.
+type Executor struct {
+	Jobs int
+
+	// FailFast stops starting new tasks as soon as one of them fails.
+	// Tasks already running are left to finish; the rest are reported
+	// with ErrStopped.
+	FailFast bool
+
+	// OnResult, if set, is called once per task as soon as it finishes,
+	// in completion order. Calls are serialized, so it needs no locking.
+	OnResult func(Result)
+}
+
+// NewExecutor returns an Executor running up to jobs tasks at once.
+// A non-positive jobs value falls back to config.DefaultJobs.
+func NewExecutor(jobs int) *Executor {
+	if jobs < 1 {
+		jobs = config.DefaultJobs
+	}
+	return &Executor{Jobs: jobs}
+}
+
+// Run executes tasks and returns their results in the same order as tasks,
+// regardless of the order in which they complete. Tasks not yet started when
+// ctx is canceled are reported with ctx.Err().
+func (e *Executor) Run(ctx context.Context, tasks []Task) []Result {
+	jobs := e.Jobs
+	if jobs < 1 {
+		jobs = config.DefaultJobs
+	}
+	if jobs > len(tasks) {
+		jobs = len(tasks)
+	}
+
+	results := make([]Result, len(tasks))
+	indexes := make(chan int)
+	done := make(chan Result)
+	var stopped atomic.Bool
+
+	var wg sync.WaitGroup
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if stopped.Load() {
+					done <- Result{Index: i, Name: tasks[i].Name, Dir: tasks[i].Dir, Remote: tasks[i].Remote, Status: StatusCanceled, Err: ErrStopped}
+					continue
+				}
+				res := runTask(ctx, i, tasks[i])
+				if res.Err != nil && e.FailFast {
+					stopped.Store(true)
+				}
+				done <- res
+			}
+		}()
+	}
+
+	go func() {
+		for i := range tasks {
+			indexes <- i
+		}
+		close(indexes)
+		wg.Wait()
+		close(done)
+	}()
+
+	for res := range done {
+		results[res.Index] = res
+		if e.OnResult != nil {
+			e.OnResult(res)
+		}
+	}
+	return results
+}
+
+func runTask(ctx context.Context, i int, t Task) Result {
+	res := Result{Index: i, Name: t.Name, Dir: t.Dir, Remote: t.Remote}
+	if err := ctx.Err(); err != nil {
+		res.Status = StatusCanceled
+		res.Err = err
+		return res
+	}
+	start := time.Now()
+	out := t.Run(ctx)
+	res.Duration = time.Since(start)
+	res.Status, res.Output, res.Stderr, res.Err = out.Status, out.Output, out.Stderr, out.Err
+	if res.Status == "" {
+		res.Status = StatusOK
+		if res.Err != nil {
+			res.Status = StatusFailed
+		}
+	}
+	return res
+}
+
+// ResultRecord is the stable, machine-readable form of a Result.
+type ResultRecord struct {
+	Name       string `json:"name"`
+	Path       string `json:"path"`   // repository directory, "" if none
+	Remote     string `json:"remote"` // origin URL, "" if unknown
+	Status     string `json:"status"`
+	ExitCode   int    `json:"exitCode"`
+	DurationMs int64  `json:"durationMs"`
+	Stdout     string `json:"stdout"` // all output when the streams were not captured apart
+	Stderr     string `json:"stderr"`
+	Error      string `json:"error"` // "" on success
+}
+
+// Record returns r as a ResultRecord.
+func (r Result) Record() ResultRecord {
+	rec := ResultRecord{
+		Name:       r.Name,
+		Path:       r.Dir,
+		Remote:     r.Remote,
+		Status:     r.Status,
+		ExitCode:   r.ExitCode(),
+		DurationMs: r.Duration.Milliseconds(),
+		Stdout:     r.Output,
+		Stderr:     r.Stderr,
+	}
+	if r.Err != nil {
+		rec.Error = r.Err.Error()
+	}
+	return rec
+}
@@ -0,0 +1,185 @@
+package ghops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestExecutorKeepsInputOrder(t *testing.T) {
+	const n = 8
+	tasks := make([]Task, n)
+	for i := range tasks {
+		i := i
+		tasks[i] = Task{
+			Name: fmt.Sprint(i),
+			Run: func(ctx context.Context) Outcome {
+				// Later tasks finish first.
+				time.Sleep(time.Duration(n-i) * 5 * time.Millisecond)
+				return Outcome{Output: fmt.Sprint(i)}
+			},
+		}
+	}
+
+	var finished []string
+	ex := NewExecutor(n)
+	ex.OnResult = func(r Result) { finished = append(finished, r.Name) }
+	results := ex.Run(context.Background(), tasks)
+
+	if len(results) != n {
+		t.Fatalf("got %d results, want %d", len(results), n)
+	}
+	for i, r := range results {
+		if r.Index != i || r.Name != fmt.Sprint(i) || r.Output != fmt.Sprint(i) {
+			t.Errorf("results[%d] = %+v", i, r)
+		}
+		if r.Status != StatusOK {
+			t.Errorf("results[%d].Status = %q, want %q", i, r.Status, StatusOK)
+		}
+	}
+	if finished[0] == "0" {
+		t.Errorf("OnResult order %v is input order, want completion order", finished)
+	}
+}
+
+func TestExecutorBoundsJobs(t *testing.T) {
+	const jobs = 3
+	var running, peak atomic.Int32
+	tasks := make([]Task, 20)
+	for i := range tasks {
+		tasks[i] = Task{Run: func(ctx context.Context) Outcome {
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(2 * time.Millisecond)
+			running.Add(-1)
+			return Outcome{}
+		}}
+	}
+
+	NewExecutor(jobs).Run(context.Background(), tasks)
+	if p := peak.Load(); p > jobs {
+		t.Errorf("%d tasks ran at once, want at most %d", p, jobs)
+	}
+	if p := peak.Load(); p < 2 {
+		t.Errorf("at most %d task ran at once, want tasks to overlap", p)
+	}
+}
+
+func TestExecutorFailFast(t *testing.T) {
+	failed := errors.New("boom")
+	release := make(chan struct{})
+	var started sync.WaitGroup
+	started.Add(2)
+
+	tasks := []Task{
+		{Name: "slow", Run: func(ctx context.Context) Outcome {
+			started.Done()
+			<-release
+			if err := ctx.Err(); err != nil {
+				return Outcome{Err: err}
+			}
+			return Outcome{Output: "finished"}
+		}},
+		{Name: "fails", Run: func(ctx context.Context) Outcome {
+			started.Done()
+			started.Wait()
+			return Outcome{Err: failed}
+		}},
+		{Name: "never", Run: func(ctx context.Context) Outcome {
+			t.Error("a task started after a failure")
+			return Outcome{}
+		}},
+		{Name: "never either", Run: func(ctx context.Context) Outcome {
+			t.Error("a task started after a failure")
+			return Outcome{}
+		}},
+	}
+
+	ex := NewExecutor(2)
+	ex.FailFast = true
+	ex.OnResult = func(r Result) {
+		if r.Name == "fails" {
+			close(release) // let the running task finish only after the failure
+		}
+	}
+	results := ex.Run(context.Background(), tasks)
+
+	if r := results[0]; r.Err != nil || r.Status != StatusOK || r.Output != "finished" {
+		t.Errorf("running task = %+v, want it to finish", r)
+	}
+	if r := results[1]; !errors.Is(r.Err, failed) || r.Status != StatusFailed {
+		t.Errorf("failing task = %+v", r)
+	}
+	for _, r := range results[2:] {
+		if !errors.Is(r.Err, ErrStopped) || r.Status != StatusCanceled || !r.NotStarted() {
+			t.Errorf("unstarted task = %+v, want ErrStopped", r)
+		}
+	}
+}
+
+func TestExecutorWithoutFailFastRunsEverything(t *testing.T) {
+	var ran atomic.Int32
+	tasks := make([]Task, 5)
+	for i := range tasks {
+		i := i
+		tasks[i] = Task{Run: func(ctx context.Context) Outcome {
+			ran.Add(1)
+			if i == 0 {
+				return Outcome{Err: errors.New("boom")}
+			}
+			return Outcome{}
+		}}
+	}
+	results := NewExecutor(1).Run(context.Background(), tasks)
+	if n := ran.Load(); n != 5 {
+		t.Errorf("%d tasks ran, want 5", n)
+	}
+	if results[0].Status != StatusFailed || results[4].Status != StatusOK {
+		t.Errorf("statuses = %q, %q", results[0].Status, results[4].Status)
+	}
+}
+
+func TestExecutorCanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results := NewExecutor(2).Run(ctx, []Task{
+		{Name: "a", Run: func(ctx context.Context) Outcome {
+			t.Error("a task started after cancellation")
+			return Outcome{}
+		}},
+	})
+	if r := results[0]; r.Status != StatusCanceled || !r.NotStarted() {
+		t.Errorf("result = %+v, want it canceled before starting", r)
+	}
+}
+
+func TestExecutorOnResultOncePerTask(t *testing.T) {
+	const n = 50
+	tasks := make([]Task, n)
+	for i := range tasks {
+		tasks[i] = Task{Name: fmt.Sprint(i), Run: func(ctx context.Context) Outcome { return Outcome{} }}
+	}
+
+	calls := map[int]int{}
+	ex := NewExecutor(4)
+	ex.OnResult = func(r Result) { calls[r.Index]++ } // serialized, so no lock
+	ex.Run(context.Background(), tasks)
+
+	if len(calls) != n {
+		t.Errorf("OnResult saw %d tasks, want %d", len(calls), n)
+	}
+	for i, c := range calls {
+		if c != 1 {
+			t.Errorf("OnResult called %d times for task %d", c, i)
+		}
+	}
+}
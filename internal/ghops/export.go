@@ -0,0 +1,59 @@
+package ghops
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/sanurb/ghpm/internal/git"
+	"github.com/sanurb/ghpm/internal/manifest"
+)
+
+// ExportManifest describes the checkouts under root as a workspace
+// manifest that ApplyWorkspace can recreate elsewhere: one repo per
+// checkout, with its origin URL, its other remotes, the branch checked
+// out and its path relative to root. The manifest has no root of its own.
+//
+// Only the normal checkouts disc selects are exported; worktrees,
+// submodules and bare repositories are not clones of their own, so they
+// are left out with a warning. Checkouts without an origin cannot be
+// cloned again; they are left out and reported in the returned warnings,
+// as are those with a detached HEAD, which are exported without a branch.
+func ExportManifest(ctx context.Context, root string, disc DiscoverOptions) (*manifest.Manifest, []string, error) {
+	found, err := discover(root, disc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed discovering repos: %w", err)
+	}
+	m := &manifest.Manifest{Version: manifest.Version}
+	var warnings []string
+	for _, f := range found {
+		p := f.Dir
+		rel := filepath.ToSlash(relPath(root, p))
+		if f.Kind != KindNormal {
+			warnings = append(warnings, fmt.Sprintf("%s: %s; left out", rel, f.Kind))
+			continue
+		}
+		remotes, err := git.Remotes(ctx, p)
+		if err != nil {
+			return nil, nil, err
+		}
+		origin, ok := remotes["origin"]
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("%s: no origin remote; left out", rel))
+			continue
+		}
+		delete(remotes, "origin")
+		if len(remotes) == 0 {
+			remotes = nil
+		}
+		branch, err := git.CurrentBranch(ctx, p)
+		if err != nil {
+			return nil, nil, err
+		}
+		if branch == "" {
+			warnings = append(warnings, fmt.Sprintf("%s: HEAD is detached; exported without a branch", rel))
+		}
+		m.Repos = append(m.Repos, manifest.Repo{URL: origin, Path: rel, Branch: branch, Remotes: remotes})
+	}
+	return m, warnings, nil
+}
@@ -0,0 +1,117 @@
+package ghops
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sanurb/ghpm/internal/github"
+)
+
+// RepoFilter selects a subset of a repository listing.
+type RepoFilter struct {
+	// Include keeps only repos whose name matches at least one glob.
+	// An empty Include keeps everything.
+	Include []string
+	// Exclude drops repos whose name matches any glob.
+	Exclude []string
+
+	NoForks    bool // drop forks
+	NoArchived bool // drop archived repos
+	// Languages keeps only repos whose primary language is one of these
+	// (case-insensitive). Empty keeps everything.
+	Languages []string
+	// Topics keeps only repos tagged with at least one of these topics.
+	// Empty keeps everything.
+	Topics []string
+	// PushedSince drops repos last pushed before this time. Zero keeps
+	// everything.
+	PushedSince time.Time
+}
+
+// Apply returns the repos that pass the filter, preserving their order.
+func (f RepoFilter) Apply(repos []github.Repo) ([]github.Repo, error) {
+	for _, p := range append(append([]string{}, f.Include...), f.Exclude...) {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid repo pattern %q: %w", p, err)
+		}
+	}
+
+	out := make([]github.Repo, 0, len(repos))
+	for _, r := range repos {
+		if f.keep(r) {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func (f RepoFilter) keep(r github.Repo) bool {
+	switch {
+	case len(f.Include) > 0 && !matchAny(f.Include, r.Name):
+		return false
+	case matchAny(f.Exclude, r.Name):
+		return false
+	case f.NoForks && r.IsFork:
+		return false
+	case f.NoArchived && r.IsArchived:
+		return false
+	case len(f.Languages) > 0 && !containsFold(f.Languages, r.Language):
+		return false
+	case !f.PushedSince.IsZero() && r.PushedAt.Before(f.PushedSince):
+		return false
+	}
+	if len(f.Topics) > 0 {
+		for _, t := range r.Topics {
+			if containsFold(f.Topics, t) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// matchAny reports whether name matches any of the glob patterns.
+// Patterns are expected to have been validated already.
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseSince turns a --pushed-since value into a cutoff time relative to
+// now. It accepts a number of days, weeks or years ("90d", "2w", "1y"),
+// any time.ParseDuration value ("36h"), or a date ("2024-01-31").
+func ParseSince(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	units := map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour, 'y': 365 * 24 * time.Hour}
+	if unit, ok := units[s[len(s)-1]]; ok {
+		if n, err := strconv.Atoi(s[:len(s)-1]); err == nil && n >= 0 {
+			return now.Add(-time.Duration(n) * unit), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid age %q (want e.g. 90d, 2w, 1y, 36h or 2024-01-31)", s)
+}
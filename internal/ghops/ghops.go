@@ -1,196 +1,193 @@
 package ghops
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io/fs"
-	"os"
 	"os/exec"
-	"path/filepath"
 	"strings"
 
+	"github.com/sanurb/ghpm/internal/config"
+	"github.com/sanurb/ghpm/internal/git"
 	"github.com/sanurb/ghpm/internal/github"
 )
 
 // GHCliError indicates an error invoking the GitHub CLI.
-type GHCliError struct {
-	Cmd string
-	Err error
+type GHCliError = github.GHCliError
+
+// Client returns the GitHub client selected by the "backend" setting.
+func Client() (github.Client, error) {
+	return github.NewClient(config.AppConfig.Backend, config.AppConfig.GitHubToken)
 }
 
-func (e *GHCliError) Error() string {
-	return fmt.Sprintf("GitHub CLI command failed (%s): %v", e.Cmd, e.Err)
+// CloneRepo clones a repository into "dest". With the gh backend it uses
+// "gh repo clone" (a normal "git clone" behind the scenes); otherwise it
+// runs git directly.
+//
+// Output is captured rather than streamed so callers such as the TUI can
+// run it without writing over the screen; on failure, the captured stderr
+// is part of the returned error. In a dry run (see git.WithPlan) the clone
+// is only recorded.
+func CloneRepo(ctx context.Context, url, dest string) error {
+	c, err := Client()
+	if err != nil {
+		return err
+	}
+	if gh, ok := c.(*github.GHClient); ok {
+		if p := git.PlanFrom(ctx); p != nil {
+			p.Add(".", "gh", "repo", "clone", url, dest)
+			return nil
+		}
+		_, err = gh.Exec(ctx, "repo", "clone", url, dest)
+	} else {
+		err = git.CloneRepo(ctx, url, dest)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to clone repo %q: %w", url, err)
+	}
+	return nil
 }
 
-func (e *GHCliError) Unwrap() error {
-	return e.Err
+// Listing is a repository listing, possibly cut short by a limit.
+type Listing struct {
+	Repos []github.Repo
+	// Limit is the limit the listing was made with; 0 means unlimited.
+	Limit int
+	// Truncated is set when more repositories exist than Limit allowed.
+	Truncated bool
 }
 
-// CloneRepo uses the GitHub CLI to clone a repository into "dest".
-// It's a normal "git clone" behind the scenes (e.g. "gh repo clone").
-func CloneRepo(url, dest string) error {
-	if err := runGHCommand("repo", "clone", url, dest); err != nil {
-		return fmt.Errorf("failed to clone repo %q: %w", url, err)
+// Warning describes the truncation of l, or returns "" if it is complete.
+func (l Listing) Warning() string {
+	if !l.Truncated {
+		return ""
 	}
-	return nil
+	return fmt.Sprintf("only the first %d repositories are listed; raise repo_limit (or pass --limit 0) to see all", l.Limit)
 }
 
-// ListSelfRepos returns the authenticated user's repositories.
-// It's effectively: gh repo list --json "name,sshUrl" -L 500
-func ListSelfRepos() ([]github.Repo, error) {
-	out, err := execGHCommand("repo", "list", "--json", "name,sshUrl", "-L", "500")
+// ListSelfRepos returns the authenticated user's repositories, at most
+// limit of them (0 for all).
+func ListSelfRepos(limit int) (Listing, error) {
+	c, err := Client()
+	if err != nil {
+		return Listing{}, err
+	}
+	l, err := listWithLimit(limit, func(n int) ([]github.Repo, error) {
+		return c.ListSelfRepos(context.Background(), n)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to list user repos: %w", err)
+		return Listing{}, fmt.Errorf("failed to list user repos: %w", err)
 	}
-	return parseRepoListJSON(out)
+	return l, nil
 }
 
-// ListPublicRepos returns the public repositories for a given username.
-// It's effectively: gh repo list <username> --public --json "name,sshUrl" -L 500
-func ListPublicRepos(username string) ([]github.Repo, error) {
+// ListPublicRepos returns the public repositories for a given username,
+// at most limit of them (0 for all).
+func ListPublicRepos(username string, limit int) (Listing, error) {
 	if username == "" {
-		return nil, fmt.Errorf("no username provided for listing public repos")
+		return Listing{}, fmt.Errorf("no username provided for listing public repos")
 	}
-	out, err := execGHCommand("repo", "list", username, "--public", "--json", "name,sshUrl", "-L", "500")
+	c, err := Client()
 	if err != nil {
-		return nil, fmt.Errorf("failed to list public repos for %q: %w", username, err)
+		return Listing{}, err
 	}
-	return parseRepoListJSON(out)
+	l, err := listWithLimit(limit, func(n int) ([]github.Repo, error) {
+		return c.ListPublicRepos(context.Background(), username, n)
+	})
+	if err != nil {
+		return Listing{}, fmt.Errorf("failed to list public repos for %q: %w", username, err)
+	}
+	return l, nil
 }
 
-func RunCommandInAllRepos(rootDir, customCmd string) error {
-	if customCmd == "" {
-		return fmt.Errorf("no custom command specified")
+// listWithLimit asks list for one repository more than limit, so that a
+// listing that hits the limit can be told apart from one that fits exactly.
+func listWithLimit(limit int, list func(n int) ([]github.Repo, error)) (Listing, error) {
+	n := 0
+	if limit > 0 {
+		n = limit + 1
 	}
-
-	// A naive approach: find local repos by searching for .git folders.
-	repos, err := discoverLocalRepos(rootDir)
+	repos, err := list(n)
 	if err != nil {
-		return fmt.Errorf("failed discovering repos: %w", err)
+		return Listing{}, err
 	}
-	if len(repos) == 0 {
-		return fmt.Errorf("no git repos found under %s", rootDir)
+	l := Listing{Repos: repos, Limit: limit}
+	if limit > 0 && len(repos) > limit {
+		l.Repos = repos[:limit]
+		l.Truncated = true
 	}
+	return l, nil
+}
 
-	for _, repoPath := range repos {
-		fmt.Printf("\n[INFO] Running %q in repo: %s\n", customCmd, repoPath)
-		// For example, run "sh -c <customCmd>" inside each repo directory
-		if err := runCommandInDir(repoPath, customCmd); err != nil {
-			// If an error in one repo is fatal, return. Or skip if you prefer partial success
-			return fmt.Errorf("error running %q in %s: %w", customCmd, repoPath, err)
-		}
-	}
+// StatusWouldRun is the status RunCommand reports in a dry run.
+const StatusWouldRun = "would run"
 
-	return nil
+// ExecOptions controls how RunCommand runs a command across repositories.
+type ExecOptions struct {
+	Jobs     int             // repositories processed in parallel
+	FailFast bool            // stop starting new repos after the first failure
+	OnResult func(Result)    // called as each repo finishes, may be nil
+	Discover DiscoverOptions // which repositories under the root to work on
 }
 
-func SetSSHRemote(rootDir, username string) error {
-	if username == "" {
-		return fmt.Errorf("no GitHub username specified")
+// RunCommand runs customCmd through "sh -c" in every repo found under
+// rootDir, capturing each repo's output. Failures in one repo do
+// not stop the others unless opts.FailFast is set. Results are returned in
+// discovery order, with stdout in Output and stderr in Stderr. In a dry run
+// (see git.WithPlan) nothing is run and the Status is StatusWouldRun.
+func RunCommand(ctx context.Context, rootDir, customCmd string, opts ExecOptions) ([]Result, error) {
+	if customCmd == "" {
+		return nil, fmt.Errorf("no custom command specified")
 	}
 
-	repos, err := discoverLocalRepos(rootDir)
+	repos, err := discoverLocalRepos(rootDir, opts.Discover)
 	if err != nil {
-		return fmt.Errorf("failed discovering repos: %w", err)
+		return nil, fmt.Errorf("failed discovering repos: %w", err)
 	}
 	if len(repos) == 0 {
-		return fmt.Errorf("no git repos found under %s", rootDir)
+		return nil, fmt.Errorf("no git repos found under %s", rootDir)
 	}
 
+	tasks := make([]Task, 0, len(repos))
 	for _, repoPath := range repos {
-		fmt.Printf("\n[INFO] Setting SSH remote for %s\n", repoPath)
-		// We try to guess the name of the repo from the existing remote url
-		oldRemote, err := getRemoteURL(repoPath, "origin")
-		if err != nil {
-			return fmt.Errorf("failed to read remote url in %s: %w", repoPath, err)
-		}
-		// a naive parse from "https://github.com/owner/repo.git" or "git@github.com:owner/repo.git"
-		owner, repoName := parseOwnerAndRepoFromURL(oldRemote)
-		if owner == "" || repoName == "" {
-			// fallback to the user if we can't parse
-			owner = username
-			repoName = filepath.Base(repoPath)
-		}
-
-		newURL := fmt.Sprintf("git@github.com:%s/%s.git", username, repoName)
-		// run: git remote set-url origin <newURL>
-		if err := runCommandInDir(repoPath, fmt.Sprintf("git remote set-url origin %s", newURL)); err != nil {
-			return fmt.Errorf("failed setting ssh remote in %s: %w", repoPath, err)
-		}
-	}
-	return nil
+		repoPath := repoPath
+		remote, _ := getRemoteURL(repoPath, "origin")
+		tasks = append(tasks, Task{
+			Name:   repoPath,
+			Dir:    repoPath,
+			Remote: remote,
+			Run: func(ctx context.Context) Outcome {
+				out, errOut, err := runCommandInDir(ctx, repoPath, customCmd)
+				if err == nil && git.IsDryRun(ctx) {
+					return Outcome{Status: StatusWouldRun}
+				}
+				return Outcome{Output: out, Stderr: errOut, Err: err}
+			},
+		})
+	}
+
+	ex := NewExecutor(opts.Jobs)
+	ex.FailFast = opts.FailFast
+	ex.OnResult = opts.OnResult
+	return ex.Run(ctx, tasks), nil
 }
 
 // -----------------------------------------------------------------------------
 // Internal helpers
 // -----------------------------------------------------------------------------
 
-// execGHCommand runs "gh" with the specified arguments and returns its stdout.
-func execGHCommand(args ...string) ([]byte, error) {
-	cmd := exec.Command("gh", args...)
-	out, err := cmd.Output()
-	if err != nil {
-		return nil, &GHCliError{
-			Cmd: fmt.Sprintf("gh %v", args),
-			Err: err,
-		}
-	}
-	return out, nil
-}
-
-func runGHCommand(args ...string) error {
-	cmd := exec.Command("gh", args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
-		return &GHCliError{
-			Cmd: fmt.Sprintf("gh %v", args),
-			Err: err,
-		}
-	}
-	return nil
-}
-
-func parseRepoListJSON(in []byte) ([]github.Repo, error) {
-	var repos []github.Repo
-	if err := json.Unmarshal(in, &repos); err != nil {
-		return nil, fmt.Errorf("failed to parse repo list JSON: %w", err)
+// runCommandInDir runs command through "sh -c" inside dir and returns its
+// stdout and stderr. In a dry run the command is only recorded.
+func runCommandInDir(ctx context.Context, dir, command string) (stdout, stderr string, err error) {
+	if p := git.PlanFrom(ctx); p != nil {
+		p.Add(dir, "sh", "-c", command)
+		return "", "", nil
 	}
-	return repos, nil
-}
-
-func runCommandInDir(dir, command string) error {
-	cmd := exec.Command("sh", "-c", command)
+	var out, errOut strings.Builder
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
 	cmd.Dir = dir
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
-}
-
-func discoverLocalRepos(root string) ([]string, error) {
-	var repos []string
-	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err // permission error, etc.
-		}
-		if !d.IsDir() {
-			return nil
-		}
-		// e.g. path/.git
-		gitPath := filepath.Join(path, ".git")
-		info, e2 := os.Stat(gitPath)
-		if e2 == nil && info.IsDir() {
-			// This is a repo
-			repos = append(repos, path)
-			// skip walking deeper into this repo
-			return fs.SkipDir
-		}
-		return nil
-	})
-	if err != nil {
-		return nil, err
-	}
-	return repos, nil
+	cmd.Stdout, cmd.Stderr = &out, &errOut
+	err = cmd.Run()
+	return out.String(), errOut.String(), err
 }
 
 // getRemoteURL returns the remote URL for the specified remoteName (e.g. 'origin').
@@ -204,19 +201,3 @@ func getRemoteURL(repoPath, remoteName string) (string, error) {
 	}
 	return strings.TrimSpace(string(out)), nil
 }
-
-func parseOwnerAndRepoFromURL(url string) (owner, repoName string) {
-	// handle https://github.com/<owner>/<repo>.git or git@github.com:<owner>/<repo>.git
-	url = strings.TrimSuffix(url, ".git")
-	if idx := strings.Index(url, "github.com"); idx != -1 {
-		after := url[idx+len("github.com"):]
-		after = strings.TrimPrefix(after, ":")
-		after = strings.TrimPrefix(after, "/")
-		parts := strings.Split(after, "/")
-		if len(parts) == 2 {
-			return parts[0], parts[1]
-		}
-	}
-	// fallback
-	return "", ""
-}
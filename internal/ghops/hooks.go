@@ -0,0 +1,80 @@
+package ghops
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/sanurb/ghpm/internal/config"
+	"github.com/sanurb/ghpm/internal/git"
+	"github.com/sanurb/ghpm/internal/github"
+)
+
+// StatusHookFailed is reported for a repository that was cloned but whose
+// post-clone hooks failed.
+const StatusHookFailed = "hook failed"
+
+// hookCommands returns the commands of the hooks that apply to r, cloned
+// into dir, in the order the hooks are listed.
+func hookCommands(ctx context.Context, hooks []config.Hook, r github.Repo, dir string) []string {
+	var cmds []string
+	for _, h := range hooks {
+		if hookMatches(ctx, h, r, dir) {
+			cmds = append(cmds, h.Run...)
+		}
+	}
+	return cmds
+}
+
+// hookMatches reports whether h applies to r, cloned into dir. In a dry
+// run nothing has been cloned, so file conditions cannot be checked and
+// are taken to hold.
+func hookMatches(ctx context.Context, h config.Hook, r github.Repo, dir string) bool {
+	if len(h.Match) > 0 && !matchAny(h.Match, r.Name) {
+		return false
+	}
+	if len(h.Language) > 0 && !containsFold(h.Language, r.Language) {
+		return false
+	}
+	if len(h.Files) > 0 && !git.IsDryRun(ctx) {
+		for _, f := range h.Files {
+			if found, _ := filepath.Glob(filepath.Join(dir, filepath.FromSlash(f))); len(found) > 0 {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// runHooks runs each hook command in dir in turn, stopping at the first
+// that fails, and returns their combined output with each command echoed
+// before its output.
+func runHooks(ctx context.Context, dir string, hooks []string) (string, error) {
+	var out strings.Builder
+	for _, h := range hooks {
+		fmt.Fprintf(&out, "$ %s\n", h)
+		stdout, stderr, err := runCommandInDir(ctx, dir, h)
+		out.WriteString(stdout)
+		out.WriteString(stderr)
+		if err != nil {
+			return out.String(), fmt.Errorf("hook %q failed: %w", h, err)
+		}
+	}
+	return out.String(), nil
+}
+
+// afterClone runs the hooks for r, just cloned into dir, and folds their
+// output and any failure into res.
+func afterClone(ctx context.Context, hooks []config.Hook, r github.Repo, dir string, res Outcome) Outcome {
+	if res.Err != nil || res.Status != StatusCloned {
+		return res
+	}
+	out, err := runHooks(ctx, dir, hookCommands(ctx, hooks, r, dir))
+	res.Output += out
+	if err != nil {
+		res.Status, res.Err = StatusHookFailed, err
+	}
+	return res
+}
@@ -0,0 +1,88 @@
+package ghops
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/sanurb/ghpm/internal/github"
+	"github.com/sanurb/ghpm/internal/remoteurl"
+)
+
+// Named layouts accepted by ParseLayout in place of a template.
+var layoutPresets = map[string]string{
+	"flat":  "{{.Name}}",
+	"owner": "{{.Owner}}/{{.Name}}",
+	"host":  "{{.Host}}/{{.Owner}}/{{.Name}}",
+}
+
+// DefaultLayout is the layout used when none is configured.
+const DefaultLayout = "flat"
+
+// Layout maps a repository to the directory it is cloned into, relative
+// to the clone root.
+type Layout struct {
+	src  string
+	tmpl *template.Template
+}
+
+// layoutData is what a layout template is executed with.
+type layoutData struct {
+	Host  string // e.g. "github.com"
+	Owner string
+	Name  string
+}
+
+// ParseLayout parses a layout: one of "flat" ({{.Name}}), "owner"
+// ({{.Owner}}/{{.Name}}) or "host" ({{.Host}}/{{.Owner}}/{{.Name}}), or a
+// text/template using those fields. "" means DefaultLayout.
+func ParseLayout(s string) (*Layout, error) {
+	if s == "" {
+		s = DefaultLayout
+	}
+	src := s
+	if preset, ok := layoutPresets[s]; ok {
+		src = preset
+	}
+	tmpl, err := template.New("layout").Parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("invalid layout %q: %w", s, err)
+	}
+	l := &Layout{src: s, tmpl: tmpl}
+	if _, err := l.Path(github.Repo{Owner: "owner", Name: "name", SSHUrl: "git@github.com:owner/name.git"}); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// String returns the layout as it was given to ParseLayout.
+func (l *Layout) String() string { return l.src }
+
+// Path returns the directory r is cloned into, relative to the clone
+// root. A nil Layout is DefaultLayout.
+func (l *Layout) Path(r github.Repo) (string, error) {
+	if l == nil {
+		return r.Name, nil
+	}
+	var b strings.Builder
+	data := layoutData{Host: repoHost(r), Owner: r.Owner, Name: r.Name}
+	if err := l.tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("invalid layout %q: %w", l.src, err)
+	}
+	p := filepath.Clean(filepath.FromSlash(b.String()))
+	if p == "." || filepath.IsAbs(p) || p == ".." || strings.HasPrefix(p, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("layout %q gives %q for %s/%s; want a relative path inside the clone root", l.src, b.String(), r.Owner, r.Name)
+	}
+	return p, nil
+}
+
+// repoHost returns the host r is served from, defaulting to github.com.
+func repoHost(r github.Repo) string {
+	for _, raw := range []string{r.HTTPSUrl, r.SSHUrl} {
+		if u, err := remoteurl.Parse(raw); err == nil {
+			return u.Host
+		}
+	}
+	return "github.com"
+}
@@ -0,0 +1,28 @@
+package ghops
+
+import (
+	"fmt"
+)
+
+// LocalRepo is a git checkout found under a root directory.
+type LocalRepo struct {
+	Path   string   `json:"path"`   // relative to the root
+	Dir    string   `json:"dir"`    // the checkout's directory
+	Remote string   `json:"remote"` // origin URL, "" if none
+	Kind   RepoKind `json:"kind"`
+}
+
+// LocalRepos returns the git checkouts under root that disc selects, in
+// discovery order.
+func LocalRepos(root string, disc DiscoverOptions) ([]LocalRepo, error) {
+	found, err := discover(root, disc)
+	if err != nil {
+		return nil, fmt.Errorf("failed discovering repos: %w", err)
+	}
+	repos := make([]LocalRepo, len(found))
+	for i, f := range found {
+		remote, _ := getRemoteURL(f.Dir, "origin")
+		repos[i] = LocalRepo{Path: relPath(root, f.Dir), Dir: f.Dir, Remote: remote, Kind: f.Kind}
+	}
+	return repos, nil
+}
@@ -0,0 +1,124 @@
+package ghops
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sanurb/ghpm/internal/git"
+)
+
+// Statuses reported by Push and Pull, in addition to StatusSkipped and
+// StatusUpToDate.
+const (
+	StatusPushed    = "pushed"
+	StatusPulled    = "pulled"
+	StatusWouldPush = "would push"
+	StatusWouldPull = "would pull"
+)
+
+// Push pushes the current branch of every repo under rootDir to its
+// upstream. Repos that are dirty, have a detached HEAD or a branch with no
+// upstream are skipped, with the reason in the Result's Output, and repos
+// with nothing to push are reported as StatusUpToDate. In a dry run (see
+// git.WithPlan), nothing is pushed; the Output lists the commits that
+// would be.
+//
+// Ahead counts come from the last fetch, so a repo whose upstream moved
+// since may still be rejected by the remote.
+func Push(ctx context.Context, rootDir string, opts ExecOptions) ([]Result, error) {
+	return forEachSyncableRepo(ctx, rootDir, opts, func(ctx context.Context, dir string, st git.Status) Outcome {
+		if st.Ahead == 0 {
+			return Outcome{Status: StatusUpToDate}
+		}
+		if git.IsDryRun(ctx) {
+			log, err := git.UnpushedCommits(ctx, dir)
+			if err != nil {
+				return Outcome{Err: err}
+			}
+			git.BatchPushRepo(ctx, dir) // recorded, not run
+			return Outcome{
+				Status: StatusWouldPush,
+				Output: fmt.Sprintf("%d commits to %s:\n%s", st.Ahead, st.Upstream, log),
+			}
+		}
+		out, err := git.BatchPushRepo(ctx, dir)
+		if err != nil {
+			return Outcome{Output: out, Err: err}
+		}
+		return Outcome{Status: StatusPushed, Output: out}
+	})
+}
+
+// Pull pulls the current branch of every repo under rootDir from its
+// upstream using mode. Repos that are dirty, have a detached HEAD or a
+// branch with no upstream are skipped, with the reason in the Result's
+// Output. In a dry run, nothing is pulled and the Output shows how far
+// behind each repo was at its last fetch.
+func Pull(ctx context.Context, rootDir string, mode git.PullMode, opts ExecOptions) ([]Result, error) {
+	return forEachSyncableRepo(ctx, rootDir, opts, func(ctx context.Context, dir string, st git.Status) Outcome {
+		if git.IsDryRun(ctx) {
+			git.BatchPullRepo(ctx, dir, mode) // recorded, not run
+			return Outcome{
+				Status: StatusWouldPull,
+				Output: fmt.Sprintf("%d commits behind %s as of the last fetch\n", st.Behind, st.Upstream),
+			}
+		}
+		before, _ := git.Run(ctx, dir, "rev-parse", "HEAD")
+		out, err := git.BatchPullRepo(ctx, dir, mode)
+		if err != nil {
+			return Outcome{Output: out, Err: err}
+		}
+		if after, _ := git.Run(ctx, dir, "rev-parse", "HEAD"); after == before {
+			return Outcome{Status: StatusUpToDate, Output: out}
+		}
+		return Outcome{Status: StatusPulled, Output: out}
+	})
+}
+
+// forEachSyncableRepo runs fn in every repo under rootDir whose current
+// branch can safely be pushed or pulled, and reports the others as
+// StatusSkipped. Result names are paths relative to rootDir.
+func forEachSyncableRepo(ctx context.Context, rootDir string, opts ExecOptions, fn func(ctx context.Context, dir string, st git.Status) Outcome) ([]Result, error) {
+	repos, err := discoverLocalRepos(rootDir, opts.Discover)
+	if err != nil {
+		return nil, fmt.Errorf("failed discovering repos: %w", err)
+	}
+	if len(repos) == 0 {
+		return nil, fmt.Errorf("no git repos found under %s", rootDir)
+	}
+
+	tasks := make([]Task, 0, len(repos))
+	for _, repoPath := range repos {
+		repoPath := repoPath
+		remote, _ := getRemoteURL(repoPath, "origin")
+		tasks = append(tasks, Task{
+			Name:   relPath(rootDir, repoPath),
+			Dir:    repoPath,
+			Remote: remote,
+			Run: func(ctx context.Context) Outcome {
+				st, err := git.GetStatus(ctx, repoPath)
+				if err != nil {
+					return Outcome{Err: err}
+				}
+				switch {
+				case st.Detached:
+					return skipped("HEAD is detached")
+				case st.Upstream == "":
+					return skipped(fmt.Sprintf("branch %s has no upstream", st.Branch))
+				case st.Dirty:
+					return skipped("working tree has uncommitted changes")
+				}
+				return fn(ctx, repoPath, st)
+			},
+		})
+	}
+
+	ex := NewExecutor(opts.Jobs)
+	ex.FailFast = opts.FailFast
+	ex.OnResult = opts.OnResult
+	return ex.Run(ctx, tasks), nil
+}
+
+func skipped(reason string) Outcome {
+	return Outcome{Status: StatusSkipped, Output: reason + "\n"}
+}
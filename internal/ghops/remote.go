@@ -0,0 +1,123 @@
+package ghops
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/sanurb/ghpm/internal/git"
+	"github.com/sanurb/ghpm/internal/remoteurl"
+)
+
+// Statuses reported by ConvertRemotes, in addition to StatusSkipped and
+// StatusUpToDate.
+const (
+	StatusConverted    = "converted"
+	StatusWouldConvert = "would convert"
+)
+
+// verifyTimeout bounds each "git ls-remote" made to verify a new URL.
+const verifyTimeout = 30 * time.Second
+
+// ConvertOptions controls ConvertRemotes.
+type ConvertOptions struct {
+	// Remote is the remote to convert; empty means "origin".
+	Remote string
+	// To is the protocol to convert to.
+	To remoteurl.Protocol
+	// Verify checks that the new URL answers "git ls-remote" before it is
+	// applied. A repo whose new URL fails the check is left unchanged.
+	Verify bool
+}
+
+// ConvertRemotes rewrites the URL of opts.Remote in every repo under
+// rootDir to protocol opts.To, keeping its host, owner and repository.
+// Each Result's Output shows the change as a "-old/+new" diff; in a dry
+// run (see git.WithPlan) nothing is changed and the Status is
+// StatusWouldConvert. Repos
+// without the remote are skipped, and those already using opts.To are
+// reported as StatusUpToDate. Result names are paths relative to rootDir.
+func ConvertRemotes(ctx context.Context, rootDir string, opts ConvertOptions, execOpts ExecOptions) ([]Result, error) {
+	remote := opts.Remote
+	if remote == "" {
+		remote = "origin"
+	}
+	repos, err := discoverLocalRepos(rootDir, execOpts.Discover)
+	if err != nil {
+		return nil, fmt.Errorf("failed discovering repos: %w", err)
+	}
+	if len(repos) == 0 {
+		return nil, fmt.Errorf("no git repos found under %s", rootDir)
+	}
+
+	tasks := make([]Task, 0, len(repos))
+	for _, repoPath := range repos {
+		repoPath := repoPath
+		origin, _ := getRemoteURL(repoPath, "origin")
+		tasks = append(tasks, Task{
+			Name:   relPath(rootDir, repoPath),
+			Dir:    repoPath,
+			Remote: origin,
+			Run: func(ctx context.Context) Outcome {
+				return convertRemote(ctx, repoPath, remote, opts)
+			},
+		})
+	}
+
+	ex := NewExecutor(execOpts.Jobs)
+	ex.FailFast = execOpts.FailFast
+	ex.OnResult = execOpts.OnResult
+	return ex.Run(ctx, tasks), nil
+}
+
+func convertRemote(ctx context.Context, dir, remote string, opts ConvertOptions) Outcome {
+	oldURL, err := getRemoteURL(dir, remote)
+	if err != nil {
+		return skipped(fmt.Sprintf("no remote named %s", remote))
+	}
+
+	u, err := remoteurl.Parse(oldURL)
+	if err != nil {
+		return Outcome{Err: err}
+	}
+	if u.Protocol == opts.To {
+		return Outcome{Status: StatusUpToDate}
+	}
+	if u.Protocol == remoteurl.SSH && opts.To != remoteurl.SSH {
+		u.Host = resolveSSHHost(ctx, u.Host)
+	}
+	newURL := u.As(opts.To).String()
+	diff := fmt.Sprintf("%s:\n- %s\n+ %s\n", remote, oldURL, newURL)
+
+	if opts.Verify {
+		vctx, cancel := context.WithTimeout(ctx, verifyTimeout)
+		defer cancel()
+		if _, err := git.LsRemote(vctx, newURL); err != nil {
+			return Outcome{Output: diff, Err: fmt.Errorf("%s does not resolve: %w", newURL, err)}
+		}
+	}
+	if out, err := git.Mutate(ctx, dir, "remote", "set-url", remote, newURL); err != nil {
+		return Outcome{Output: diff + out, Err: err}
+	}
+	if git.IsDryRun(ctx) {
+		return Outcome{Status: StatusWouldConvert, Output: diff}
+	}
+	return Outcome{Status: StatusConverted, Output: diff}
+}
+
+// resolveSSHHost returns the real host name behind an SSH host alias such
+// as "github-work", as configured in ~/.ssh/config, or host itself.
+func resolveSSHHost(ctx context.Context, host string) string {
+	out, err := exec.CommandContext(ctx, "ssh", "-G", host).Output()
+	if err != nil {
+		return host
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if name, ok := strings.CutPrefix(line, "hostname "); ok && name != "" {
+			return strings.TrimSpace(name)
+		}
+	}
+	return host
+}
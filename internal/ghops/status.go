@@ -0,0 +1,253 @@
+package ghops
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sanurb/ghpm/internal/git"
+)
+
+// RepoStatus is the status of one local checkout.
+type RepoStatus struct {
+	// Path is the checkout's path relative to the root it was found under.
+	Path string
+	// Dir is the checkout's directory.
+	Dir string
+	// Remote is the checkout's origin URL, "" if it has none.
+	Remote string
+	git.Status
+	// Err is set when the status could not be read; Status is then zero.
+	Err error
+}
+
+// Unpushed reports whether the checkout holds work that exists nowhere
+// else: uncommitted or untracked changes, stashes, commits its upstream
+// lacks, or a branch with no upstream at all.
+func (s RepoStatus) Unpushed() bool {
+	return s.Err == nil && (s.Dirty || s.Untracked > 0 || s.Stashes > 0 || s.Ahead > 0 ||
+		s.Detached || s.Upstream == "")
+}
+
+// LocalStatus reads the status of every repository under root that disc
+// selects, jobs at a time. Statuses are sorted by path. Nothing is fetched, so ahead/behind
+// counts are as of each repository's last fetch.
+func LocalStatus(ctx context.Context, root string, disc DiscoverOptions, jobs int) ([]RepoStatus, error) {
+	paths, err := discoverLocalRepos(root, disc)
+	if err != nil {
+		return nil, fmt.Errorf("failed discovering repos: %w", err)
+	}
+
+	statuses := make([]RepoStatus, len(paths))
+	tasks := make([]Task, len(paths))
+	for i, p := range paths {
+		i, p := i, p
+		tasks[i] = Task{
+			Name: relPath(root, p),
+			Dir:  p,
+			Run: func(ctx context.Context) Outcome {
+				st, err := git.GetStatus(ctx, p)
+				remote, _ := getRemoteURL(p, "origin")
+				statuses[i] = RepoStatus{Path: relPath(root, p), Dir: p, Remote: remote, Status: st, Err: err}
+				return Outcome{Err: err}
+			},
+		}
+	}
+	NewExecutor(jobs).Run(ctx, tasks)
+	for i, r := range statuses {
+		if r.Path == "" { // canceled before it ran
+			remote, _ := getRemoteURL(paths[i], "origin")
+			statuses[i] = RepoStatus{Path: relPath(root, paths[i]), Dir: paths[i], Remote: remote, Err: ctx.Err()}
+		}
+	}
+
+	SortStatuses(statuses, "name")
+	return statuses, nil
+}
+
+// StatusSortKeys lists the keys SortStatuses accepts.
+var StatusSortKeys = []string{"name", "branch", "ahead", "behind", "dirty", "stash", "age"}
+
+// SortStatuses sorts statuses in place by key, one of StatusSortKeys.
+// Numeric keys and "dirty" put the largest values first and "age" the
+// least recently committed; ties are broken by path.
+func SortStatuses(statuses []RepoStatus, key string) error {
+	var cmp func(a, b RepoStatus) int
+	switch key {
+	case "", "name":
+		cmp = func(a, b RepoStatus) int { return 0 }
+	case "branch":
+		cmp = func(a, b RepoStatus) int { return strings.Compare(a.Branch, b.Branch) }
+	case "ahead":
+		cmp = func(a, b RepoStatus) int { return b.Ahead - a.Ahead }
+	case "behind":
+		cmp = func(a, b RepoStatus) int { return b.Behind - a.Behind }
+	case "dirty":
+		cmp = func(a, b RepoStatus) int { return dirtiness(b) - dirtiness(a) }
+	case "stash":
+		cmp = func(a, b RepoStatus) int { return b.Stashes - a.Stashes }
+	case "age":
+		cmp = func(a, b RepoStatus) int { return a.LastCommit.Compare(b.LastCommit) }
+	default:
+		return fmt.Errorf("invalid sort key %q (want one of %s)", key, strings.Join(StatusSortKeys, ", "))
+	}
+	sort.SliceStable(statuses, func(i, j int) bool {
+		if c := cmp(statuses[i], statuses[j]); c != 0 {
+			return c < 0
+		}
+		return statuses[i].Path < statuses[j].Path
+	})
+	return nil
+}
+
+// dirtiness ranks modified tracked files above untracked files alone.
+func dirtiness(s RepoStatus) int {
+	n := s.Untracked
+	if s.Dirty {
+		n += 1 << 20
+	}
+	return n
+}
+
+// StatusFilter selects repository statuses. The boolean fields are
+// alternatives: a status is kept if it matches any of those set, or all
+// statuses are kept if none is set. Match applies on top of them.
+type StatusFilter struct {
+	// Match is a glob matched against the path and its base name.
+	Match string
+
+	Dirty    bool // uncommitted or untracked changes
+	Ahead    bool // commits not on the upstream
+	Behind   bool // upstream commits not merged
+	Detached bool // detached HEAD
+	Stashed  bool // at least one stash
+	Unpushed bool // see RepoStatus.Unpushed
+}
+
+// Apply returns the statuses matching f, in order.
+func (f StatusFilter) Apply(statuses []RepoStatus) []RepoStatus {
+	var out []RepoStatus
+	for _, s := range statuses {
+		if f.keep(s) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func (f StatusFilter) keep(s RepoStatus) bool {
+	if f.Match != "" && !matchAny([]string{f.Match}, s.Path) && !matchAny([]string{f.Match}, filepath.Base(s.Path)) {
+		return false
+	}
+	if !(f.Dirty || f.Ahead || f.Behind || f.Detached || f.Stashed || f.Unpushed) {
+		return true
+	}
+	return f.Dirty && (s.Dirty || s.Untracked > 0) ||
+		f.Ahead && s.Ahead > 0 ||
+		f.Behind && s.Behind > 0 ||
+		f.Detached && s.Detached ||
+		f.Stashed && s.Stashes > 0 ||
+		f.Unpushed && s.Unpushed()
+}
+
+// StatusRecord is the stable, machine-readable form of a RepoStatus.
+type StatusRecord struct {
+	Path       string     `json:"path"`
+	Remote     string     `json:"remote"` // origin URL, "" if none
+	Branch     string     `json:"branch"`
+	Detached   bool       `json:"detached"`
+	Upstream   string     `json:"upstream"`
+	Ahead      int        `json:"ahead"`
+	Behind     int        `json:"behind"`
+	Dirty      bool       `json:"dirty"`
+	Untracked  int        `json:"untracked"`
+	Stashes    int        `json:"stashes"`
+	LastCommit *time.Time `json:"lastCommit"` // null in an empty repository
+	Error      string     `json:"error"`
+}
+
+// Record returns s as a StatusRecord.
+func (s RepoStatus) Record() StatusRecord {
+	rec := StatusRecord{
+		Path:      s.Path,
+		Remote:    s.Remote,
+		Branch:    s.Branch,
+		Detached:  s.Detached,
+		Upstream:  s.Upstream,
+		Ahead:     s.Ahead,
+		Behind:    s.Behind,
+		Dirty:     s.Dirty,
+		Untracked: s.Untracked,
+		Stashes:   s.Stashes,
+	}
+	if !s.LastCommit.IsZero() {
+		t := s.LastCommit.UTC()
+		rec.LastCommit = &t
+	}
+	if s.Err != nil {
+		rec.Error = s.Err.Error()
+	}
+	return rec
+}
+
+// StatusColumns are the headings matching RepoStatus.Row.
+var StatusColumns = []string{"REPO", "BRANCH", "AHEAD", "BEHIND", "DIRTY", "STASH", "LAST COMMIT"}
+
+// Row formats s as table cells, with the last commit shown relative to
+// now.
+func (s RepoStatus) Row(now time.Time) []string {
+	if s.Err != nil {
+		return []string{s.Path, "error: " + firstLine(s.Err.Error()), "", "", "", "", ""}
+	}
+	branch := s.Branch
+	if s.Detached {
+		branch = "(detached)"
+	}
+	ahead, behind := "-", "-"
+	if s.Upstream != "" {
+		ahead, behind = strconv.Itoa(s.Ahead), strconv.Itoa(s.Behind)
+	}
+	var dirty []string
+	if s.Dirty {
+		dirty = append(dirty, "modified")
+	}
+	if s.Untracked > 0 {
+		dirty = append(dirty, fmt.Sprintf("%d untracked", s.Untracked))
+	}
+	if len(dirty) == 0 {
+		dirty = append(dirty, "clean")
+	}
+	return []string{s.Path, branch, ahead, behind, strings.Join(dirty, ", "), strconv.Itoa(s.Stashes), Age(s.LastCommit, now)}
+}
+
+// Age formats the time elapsed between t and now in its largest unit,
+// e.g. "5m ago" or "3w ago"; the zero time formats as "never".
+func Age(t, now time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	d := now.Sub(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d/time.Minute))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d/time.Hour))
+	case d < 7*24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(d/(24*time.Hour)))
+	case d < 365*24*time.Hour:
+		return fmt.Sprintf("%dw ago", int(d/(7*24*time.Hour)))
+	default:
+		return fmt.Sprintf("%dy ago", int(d/(365*24*time.Hour)))
+	}
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
@@ -0,0 +1,189 @@
+package ghops
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sanurb/ghpm/internal/config"
+	"github.com/sanurb/ghpm/internal/git"
+	"github.com/sanurb/ghpm/internal/github"
+	"github.com/sanurb/ghpm/internal/remoteurl"
+)
+
+// Statuses reported by Sync, in addition to those of CloneRepos.
+const (
+	StatusUpToDate  = "up-to-date"
+	StatusAttention = "needs attention" // dirty, diverged or not tracking an upstream
+	StatusLocalOnly = "local only"      // checked out locally, missing from the listing
+)
+
+// SyncOptions controls Sync.
+type SyncOptions struct {
+	// Root is the directory holding the local checkouts.
+	Root string
+	// Owner is the user or organization the listing belongs to. Local
+	// repos with an origin under Owner but missing from the listing are
+	// reported as StatusLocalOnly.
+	Owner string
+	// Layout places missing repositories under Root; nil means
+	// DefaultLayout.
+	Layout *Layout
+	// Jobs is the number of repositories processed at once.
+	Jobs int
+	// Discover selects the checkouts under Root to sync. Listed
+	// repositories whose layout path it does not select are left out too.
+	Discover DiscoverOptions
+	// Hooks run in each repository that is cloned; see CloneOptions.
+	Hooks []config.Hook
+	// OnResult, if set, is called as each repository finishes.
+	OnResult func(Result)
+}
+
+// Sync makes the checkouts under opts.Root match the remote listing repos:
+// repositories without a local checkout are cloned where opts.Layout puts
+// them, and existing checkouts are fetched and fast-forwarded when that is
+// safe. Checkouts with local changes, diverged history, a detached HEAD or
+// no upstream are left alone and reported as StatusAttention, with the
+// reason in the Result's Output.
+//
+// Local checkouts are matched to remote repositories by their origin URL,
+// so they are found wherever they live under Root. Result names are paths
+// relative to Root.
+func Sync(ctx context.Context, repos []github.Repo, opts SyncOptions) ([]Result, error) {
+	disc, err := opts.Discover.forRoot(opts.Root)
+	if err != nil {
+		return nil, err
+	}
+	// A root that does not exist yet, as in a dry run into a new
+	// directory, holds no checkouts: every repository is cloned.
+	var found []foundRepo
+	if _, err := os.Stat(opts.Root); !os.IsNotExist(err) {
+		found, err = discover(opts.Root, opts.Discover)
+		if err != nil {
+			return nil, fmt.Errorf("failed discovering repos: %w", err)
+		}
+	}
+
+	type checkout struct{ dir, origin string }
+	local := make(map[string]checkout, len(found)) // by lowercase "owner/name"
+	for _, f := range found {
+		// Worktrees and submodules share an origin with the checkout
+		// they belong to, which is the one to sync.
+		if f.Kind != KindNormal {
+			continue
+		}
+		origin, err := getRemoteURL(f.Dir, "origin")
+		if err != nil {
+			continue
+		}
+		if u, err := remoteurl.Parse(origin); err == nil {
+			key := strings.ToLower(u.FullName())
+			if _, ok := local[key]; !ok {
+				local[key] = checkout{f.Dir, origin}
+			}
+		}
+	}
+
+	var tasks []Task
+	for _, r := range repos {
+		r := r
+		key := strings.ToLower(r.Owner + "/" + r.Name)
+		if c, ok := local[key]; ok {
+			delete(local, key)
+			tasks = append(tasks, Task{
+				Name:   relPath(opts.Root, c.dir),
+				Dir:    c.dir,
+				Remote: c.origin,
+				Run: func(ctx context.Context) Outcome {
+					return fastForward(ctx, c.dir)
+				},
+			})
+			continue
+		}
+		rel, err := opts.Layout.Path(r)
+		if err != nil {
+			return nil, err
+		}
+		if !disc.selects(rel) {
+			continue
+		}
+		dest := filepath.Join(opts.Root, rel)
+		tasks = append(tasks, Task{
+			Name:   relPath(opts.Root, dest),
+			Dir:    dest,
+			Remote: r.SSHUrl,
+			Run: func(ctx context.Context) Outcome {
+				return afterClone(ctx, opts.Hooks, r, dest, cloneOrUpdate(ctx, r, dest, ConflictFail))
+			},
+		})
+	}
+
+	// Whatever is left is checked out locally but was not listed.
+	for key, c := range local {
+		if opts.Owner == "" || !strings.HasPrefix(key, strings.ToLower(opts.Owner)+"/") {
+			continue
+		}
+		tasks = append(tasks, Task{
+			Name:   relPath(opts.Root, c.dir),
+			Dir:    c.dir,
+			Remote: c.origin,
+			Run: func(ctx context.Context) Outcome {
+				return Outcome{Status: StatusLocalOnly, Output: "not in the remote listing (deleted, renamed or filtered out?)\n"}
+			},
+		})
+	}
+
+	ex := NewExecutor(opts.Jobs)
+	ex.OnResult = opts.OnResult
+	return ex.Run(ctx, tasks), nil
+}
+
+// fastForward fetches the repository at dir and fast-forwards its current
+// branch to its upstream if, and only if, that cannot lose work.
+func fastForward(ctx context.Context, dir string) Outcome {
+	if out, err := git.Mutate(ctx, dir, "fetch", "--prune", "--quiet"); err != nil {
+		return Outcome{Output: out, Err: err}
+	}
+	st, err := git.GetStatus(ctx, dir)
+	if err != nil {
+		return Outcome{Err: err}
+	}
+
+	switch {
+	case st.Detached:
+		return attention("HEAD is detached")
+	case st.Upstream == "":
+		return attention(fmt.Sprintf("branch %s has no upstream", st.Branch))
+	case st.Dirty:
+		return attention("working tree has uncommitted changes")
+	case st.Diverged():
+		return attention(fmt.Sprintf("%s has diverged from %s (%d ahead, %d behind)", st.Branch, st.Upstream, st.Ahead, st.Behind))
+	case st.Behind == 0:
+		if st.Ahead > 0 {
+			return Outcome{Status: StatusUpToDate, Output: fmt.Sprintf("%d commits ahead of %s\n", st.Ahead, st.Upstream)}
+		}
+		return Outcome{Status: StatusUpToDate}
+	}
+
+	out, err := git.Mutate(ctx, dir, "merge", "--ff-only", "--quiet", "@{upstream}")
+	if err != nil {
+		return Outcome{Output: out, Err: err}
+	}
+	return Outcome{Status: StatusUpdated, Output: fmt.Sprintf("fast-forwarded %s by %d commits\n", st.Branch, st.Behind)}
+}
+
+func attention(reason string) Outcome {
+	return Outcome{Status: StatusAttention, Output: reason + "\n"}
+}
+
+// relPath returns path relative to root, or path itself if it is not
+// under root.
+func relPath(root, path string) string {
+	if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
@@ -1,23 +1,140 @@
 package git
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
 	"os/exec"
+	"strings"
 )
 
+// Run runs git with args inside dir and returns its combined output.
+// On failure, the output is included in the returned error.
+func Run(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return string(out), fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, msg)
+		}
+		return string(out), fmt.Errorf("git %s: %w", strings.Join(args, " "), err)
+	}
+	return string(out), nil
+}
+
+// IsRepo reports whether dir is the top level of a git work tree.
+func IsRepo(dir string) bool {
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return false
+	}
+	topInfo, err := os.Stat(strings.TrimSpace(string(out)))
+	if err != nil {
+		return false
+	}
+	dirInfo, err := os.Stat(dir)
+	return err == nil && os.SameFile(topInfo, dirInfo)
+}
+
 // CloneRepo clones a repository from the given URL into the destination directory.
-func CloneRepo(url, dest string) error {
-	cmd := exec.Command("git", "clone", url, dest)
-	return cmd.Run()
+// On failure, git's output is included in the returned error. In a dry run
+// (see WithPlan) the clone is only recorded.
+func CloneRepo(ctx context.Context, url, dest string) error {
+	if p := PlanFrom(ctx); p != nil {
+		p.Add(".", "git", "clone", url, dest)
+		return nil
+	}
+	cmd := exec.CommandContext(ctx, "git", "clone", url, dest)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// BatchPushRepo pushes the current branch of the repository at repoDir to
+// its upstream and returns git's output.
+func BatchPushRepo(ctx context.Context, repoDir string) (string, error) {
+	return Mutate(ctx, repoDir, "push")
+}
+
+// PullMode says how BatchPullRepo integrates upstream changes.
+type PullMode string
+
+const (
+	PullDefault PullMode = ""        // whatever pull.rebase/pull.ff configure
+	PullRebase  PullMode = "rebase"  // rebase local commits onto the upstream
+	PullFFOnly  PullMode = "ff-only" // refuse anything but a fast-forward
+)
+
+// BatchPullRepo pulls updates for the repository at repoDir and returns
+// git's output.
+func BatchPullRepo(ctx context.Context, repoDir string, mode PullMode) (string, error) {
+	args := []string{"pull"}
+	if mode != PullDefault {
+		args = append(args, "--"+string(mode))
+	}
+	return Mutate(ctx, repoDir, args...)
+}
+
+// UnpushedCommits returns the one-line log of the commits on the current
+// branch that its upstream lacks.
+func UnpushedCommits(ctx context.Context, repoDir string) (string, error) {
+	return Run(ctx, repoDir, "log", "--oneline", "--no-decorate", "@{upstream}..HEAD")
+}
+
+// LsRemote checks that url answers as a git remote, without prompting for
+// credentials, and returns git's output.
+func LsRemote(ctx context.Context, url string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "ls-remote", "--quiet", url, "HEAD")
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return string(out), fmt.Errorf("git ls-remote: %w: %s", err, msg)
+		}
+		return string(out), fmt.Errorf("git ls-remote: %w", err)
+	}
+	return string(out), nil
 }
 
-// BatchPushRepo pushes changes for the repository located at repoDir.
-func BatchPushRepo(repoDir string) error {
-	cmd := exec.Command("git", "-C", repoDir, "push")
-	return cmd.Run()
+// Remotes returns the fetch URL of every remote of the repository at
+// repoDir, by remote name.
+func Remotes(ctx context.Context, repoDir string) (map[string]string, error) {
+	out, err := Run(ctx, repoDir, "config", "--get-regexp", `^remote\..*\.url$`)
+	remotes := map[string]string{}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return remotes, nil // no remotes
+		}
+		return nil, err
+	}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		key, url, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(key, "remote."), ".url")
+		remotes[name] = url
+	}
+	return remotes, nil
 }
 
-// BatchPullRepo pulls updates for the repository located at repoDir.
-func BatchPullRepo(repoDir string) error {
-	cmd := exec.Command("git", "-C", repoDir, "pull")
-	return cmd.Run()
+// CurrentBranch returns the branch checked out in the repository at
+// repoDir, or "" if HEAD is detached.
+func CurrentBranch(ctx context.Context, repoDir string) (string, error) {
+	out, err := Run(ctx, repoDir, "symbolic-ref", "--quiet", "--short", "HEAD")
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return "", nil
+		}
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
 }
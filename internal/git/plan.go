@@ -0,0 +1,89 @@
+package git
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Plan collects the commands a dry run would have run. Mutating
+// operations check the context they are given for a Plan (see WithPlan)
+// and, when there is one, record their command in it instead of running
+// it. A Plan is safe for concurrent use.
+type Plan struct {
+	mu    sync.Mutex
+	steps []Step
+}
+
+// Step is one command recorded in a Plan.
+type Step struct {
+	Dir  string   // directory the command would run in
+	Args []string // program and arguments, e.g. ["git", "push"]
+}
+
+// String renders the command as it could be typed into a shell.
+func (s Step) String() string {
+	quoted := make([]string, len(s.Args))
+	for i, a := range s.Args {
+		quoted[i] = ShellQuote(a)
+	}
+	return strings.Join(quoted, " ")
+}
+
+type planKey struct{}
+
+// WithPlan returns a context that makes mutating operations record their
+// commands in p rather than run them.
+func WithPlan(ctx context.Context, p *Plan) context.Context {
+	return context.WithValue(ctx, planKey{}, p)
+}
+
+// PlanFrom returns the Plan carried by ctx, or nil if ctx is not a dry run.
+func PlanFrom(ctx context.Context) *Plan {
+	p, _ := ctx.Value(planKey{}).(*Plan)
+	return p
+}
+
+// IsDryRun reports whether ctx carries a Plan.
+func IsDryRun(ctx context.Context) bool {
+	return PlanFrom(ctx) != nil
+}
+
+// Add records a command run in dir.
+func (p *Plan) Add(dir string, args ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.steps = append(p.steps, Step{Dir: dir, Args: args})
+}
+
+// Steps returns the recorded commands grouped by directory, in the order
+// they were recorded within each directory.
+func (p *Plan) Steps() []Step {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	steps := append([]Step(nil), p.steps...)
+	sort.SliceStable(steps, func(i, j int) bool { return steps[i].Dir < steps[j].Dir })
+	return steps
+}
+
+// Mutate runs a git command that changes something, like Run. If ctx
+// carries a Plan, the command is recorded instead and Mutate returns "".
+func Mutate(ctx context.Context, dir string, args ...string) (string, error) {
+	if p := PlanFrom(ctx); p != nil {
+		p.Add(dir, append([]string{"git"}, args...)...)
+		return "", nil
+	}
+	return Run(ctx, dir, args...)
+}
+
+// ShellQuote quotes s for a POSIX shell if it contains anything but safe
+// characters. Braces count as unsafe, since bash expands them.
+func ShellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./=:,+@%", r))
+	}) == -1 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
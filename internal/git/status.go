@@ -0,0 +1,86 @@
+package git
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Status is a snapshot of a repository's branch and working tree.
+type Status struct {
+	Branch    string // current branch, "" when HEAD is detached
+	Detached  bool
+	Upstream  string // e.g. "origin/main", "" when the branch tracks nothing
+	Ahead     int    // commits on the branch not on its upstream
+	Behind    int    // commits on the upstream not on the branch
+	Dirty     bool   // tracked files are modified, staged or conflicted
+	Untracked int    // number of untracked files
+
+	Stashes    int       // number of stash entries
+	LastCommit time.Time // committer date of HEAD, zero in an empty repo
+}
+
+// Diverged reports whether the branch and its upstream both have commits
+// the other lacks.
+func (s Status) Diverged() bool {
+	return s.Ahead > 0 && s.Behind > 0
+}
+
+// GetStatus reads the status of the repository at dir using
+// "git status --porcelain=v2 --branch", plus its stash count and the date
+// of its last commit. Ahead/behind counts reflect the last fetch.
+func GetStatus(ctx context.Context, dir string) (Status, error) {
+	out, err := Run(ctx, dir, "status", "--porcelain=v2", "--branch")
+	if err != nil {
+		return Status{}, err
+	}
+	st, err := parseStatus(out)
+	if err != nil {
+		return st, err
+	}
+
+	// Both fail harmlessly when there are no stashes or no commits yet.
+	if out, err := Run(ctx, dir, "rev-list", "--walk-reflogs", "--count", "refs/stash"); err == nil {
+		st.Stashes, _ = strconv.Atoi(strings.TrimSpace(out))
+	}
+	if out, err := Run(ctx, dir, "log", "-1", "--format=%ct"); err == nil {
+		if secs, err := strconv.ParseInt(strings.TrimSpace(out), 10, 64); err == nil {
+			st.LastCommit = time.Unix(secs, 0)
+		}
+	}
+	return st, nil
+}
+
+func parseStatus(out string) (Status, error) {
+	var st Status
+	sc := bufio.NewScanner(strings.NewReader(out))
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.HasPrefix(line, "# branch.head "):
+			head := strings.TrimPrefix(line, "# branch.head ")
+			if head == "(detached)" {
+				st.Detached = true
+			} else {
+				st.Branch = head
+			}
+		case strings.HasPrefix(line, "# branch.upstream "):
+			st.Upstream = strings.TrimPrefix(line, "# branch.upstream ")
+		case strings.HasPrefix(line, "# branch.ab "):
+			var ahead, behind string
+			if _, err := fmt.Sscan(strings.TrimPrefix(line, "# branch.ab "), &ahead, &behind); err != nil {
+				return st, fmt.Errorf("unexpected status line %q", line)
+			}
+			st.Ahead, _ = strconv.Atoi(strings.TrimPrefix(ahead, "+"))
+			st.Behind, _ = strconv.Atoi(strings.TrimPrefix(behind, "-"))
+		case strings.HasPrefix(line, "? "):
+			st.Untracked++
+		case strings.HasPrefix(line, "1 "), strings.HasPrefix(line, "2 "), strings.HasPrefix(line, "u "):
+			st.Dirty = true
+		}
+	}
+	return st, sc.Err()
+}
@@ -0,0 +1,70 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// Client lists repositories and organizations on GitHub.
+//
+// The repository listings return at most limit repositories, following
+// pagination until then; a limit of 0 returns every repository.
+type Client interface {
+	// ListSelfRepos returns the authenticated user's repositories.
+	ListSelfRepos(ctx context.Context, limit int) ([]Repo, error)
+	// ListPublicRepos returns the public repositories owned by username.
+	ListPublicRepos(ctx context.Context, username string, limit int) ([]Repo, error)
+	// ListOrgRepos returns the repositories of the organization orgLogin.
+	ListOrgRepos(ctx context.Context, orgLogin string, limit int) ([]Repo, error)
+	// ListUserOrgs returns the organizations the authenticated user belongs to.
+	ListUserOrgs(ctx context.Context) ([]Org, error)
+}
+
+// Backend names accepted by NewClient.
+const (
+	BackendAuto = "auto" // gh when installed, the REST API otherwise
+	BackendGH   = "gh"   // shell out to the GitHub CLI
+	BackendAPI  = "api"  // talk to the REST API directly
+)
+
+// ValidateBackend returns an error unless NewClient accepts backend; ""
+// stands for BackendAuto.
+func ValidateBackend(backend string) error {
+	switch backend {
+	case "", BackendAuto, BackendGH, BackendAPI:
+		return nil
+	}
+	return fmt.Errorf("unknown GitHub backend %q (want %s, %s or %s)", backend, BackendAuto, BackendGH, BackendAPI)
+}
+
+// NewClient returns the Client for backend. token authenticates either
+// backend; when empty, the REST backend falls back to GITHUB_TOKEN and
+// GH_TOKEN, and gh uses its own login.
+func NewClient(backend, token string) (Client, error) {
+	if err := ValidateBackend(backend); err != nil {
+		return nil, err
+	}
+	switch backend {
+	case "", BackendAuto:
+		if _, err := exec.LookPath("gh"); err == nil {
+			return &GHClient{Token: token}, nil
+		}
+		return NewRESTClient(tokenOrEnv(token)), nil
+	case BackendGH:
+		return &GHClient{Token: token}, nil
+	default: // BackendAPI
+		return NewRESTClient(tokenOrEnv(token)), nil
+	}
+}
+
+func tokenOrEnv(token string) string {
+	if token != "" {
+		return token
+	}
+	if t := os.Getenv("GITHUB_TOKEN"); t != "" {
+		return t
+	}
+	return os.Getenv("GH_TOKEN")
+}
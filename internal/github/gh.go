@@ -0,0 +1,191 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GHCliError indicates an error invoking the GitHub CLI.
+type GHCliError struct {
+	Cmd    string
+	Err    error
+	Stderr string // captured stderr, if any
+}
+
+func (e *GHCliError) Error() string {
+	if e.Stderr != "" {
+		return fmt.Sprintf("GitHub CLI command failed (%s): %v: %s", e.Cmd, e.Err, e.Stderr)
+	}
+	return fmt.Sprintf("GitHub CLI command failed (%s): %v", e.Cmd, e.Err)
+}
+
+func (e *GHCliError) Unwrap() error {
+	return e.Err
+}
+
+// GHClient is the Client backed by the GitHub CLI ("gh").
+type GHClient struct {
+	// Token, if set, is passed to gh as GH_TOKEN so it takes precedence
+	// over gh's own login.
+	Token string
+}
+
+// ghUnlimited is passed to "gh repo list -L" for unlimited listings; gh
+// requires a positive limit and pages through results until it is reached.
+const ghUnlimited = 1 << 30
+
+// ghRepoFields are the "gh repo list --json" fields decoded into a Repo.
+const ghRepoFields = "name,owner,sshUrl,url,description,visibility,isFork,isArchived," +
+	"defaultBranchRef,primaryLanguage,repositoryTopics,diskUsage,pushedAt"
+
+// ghRepo is a repository as printed by "gh repo list --json".
+type ghRepo struct {
+	Name  string `json:"name"`
+	Owner struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+	SSHUrl           string `json:"sshUrl"`
+	URL              string `json:"url"`
+	Description      string `json:"description"`
+	Visibility       string `json:"visibility"`
+	IsFork           bool   `json:"isFork"`
+	IsArchived       bool   `json:"isArchived"`
+	DefaultBranchRef struct {
+		Name string `json:"name"`
+	} `json:"defaultBranchRef"`
+	PrimaryLanguage *struct {
+		Name string `json:"name"`
+	} `json:"primaryLanguage"`
+	RepositoryTopics []struct {
+		Name string `json:"name"`
+	} `json:"repositoryTopics"`
+	DiskUsage int       `json:"diskUsage"`
+	PushedAt  time.Time `json:"pushedAt"`
+}
+
+func (r ghRepo) toRepo() Repo {
+	repo := Repo{
+		Name:          r.Name,
+		Owner:         r.Owner.Login,
+		SSHUrl:        r.SSHUrl,
+		HTTPSUrl:      r.URL + ".git",
+		Description:   r.Description,
+		Visibility:    strings.ToLower(r.Visibility),
+		IsFork:        r.IsFork,
+		IsArchived:    r.IsArchived,
+		DefaultBranch: r.DefaultBranchRef.Name,
+		DiskUsage:     r.DiskUsage,
+		PushedAt:      r.PushedAt,
+	}
+	if r.PrimaryLanguage != nil {
+		repo.Language = r.PrimaryLanguage.Name
+	}
+	for _, t := range r.RepositoryTopics {
+		repo.Topics = append(repo.Topics, t.Name)
+	}
+	return repo
+}
+
+// ListSelfRepos is effectively: gh repo list --json <fields> -L <limit>
+func (c *GHClient) ListSelfRepos(ctx context.Context, limit int) ([]Repo, error) {
+	return c.repoList(ctx, nil, limit)
+}
+
+// ListPublicRepos is effectively: gh repo list <username> --public --json <fields> -L <limit>
+func (c *GHClient) ListPublicRepos(ctx context.Context, username string, limit int) ([]Repo, error) {
+	return c.repoList(ctx, []string{username, "--public"}, limit)
+}
+
+// ListOrgRepos is effectively: gh repo list <org> --json <fields> -L <limit>
+func (c *GHClient) ListOrgRepos(ctx context.Context, orgLogin string, limit int) ([]Repo, error) {
+	return c.repoList(ctx, []string{orgLogin}, limit)
+}
+
+// repoList runs "gh repo list" with extra arguments and the given limit.
+func (c *GHClient) repoList(ctx context.Context, extra []string, limit int) ([]Repo, error) {
+	if limit <= 0 {
+		limit = ghUnlimited
+	}
+	args := append([]string{"repo", "list"}, extra...)
+	args = append(args, "--json", ghRepoFields, "-L", strconv.Itoa(limit))
+	out, err := c.Exec(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseRepoListJSON(out)
+}
+
+// ListUserOrgs relies on "gh api user/orgs" rather than "gh org list",
+// because the latter does not currently support JSON output.
+//
+// See: https://docs.github.com/en/rest/orgs/orgs#list-organizations-for-the-authenticated-user
+func (c *GHClient) ListUserOrgs(ctx context.Context) ([]Org, error) {
+	out, err := c.Exec(ctx, "api", "--paginate", "user/orgs")
+	if err != nil {
+		return nil, err
+	}
+	orgs, err := decodeJSONArrays[Org](out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse orgs: %w", err)
+	}
+	return orgs, nil
+}
+
+// Exec runs "gh" with the specified arguments and returns its stdout.
+// On failure, the returned *GHCliError carries gh's stderr.
+func (c *GHClient) Exec(ctx context.Context, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "gh", args...)
+	if c.Token != "" {
+		cmd.Env = append(os.Environ(), "GH_TOKEN="+c.Token)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		cliErr := &GHCliError{
+			Cmd: fmt.Sprintf("gh %v", args),
+			Err: err,
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			cliErr.Stderr = strings.TrimSpace(string(exitErr.Stderr))
+		}
+		return nil, cliErr
+	}
+	return out, nil
+}
+
+func parseRepoListJSON(in []byte) ([]Repo, error) {
+	var list []ghRepo
+	if err := json.Unmarshal(in, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse repo list JSON: %w", err)
+	}
+	repos := make([]Repo, 0, len(list))
+	for _, r := range list {
+		repos = append(repos, r.toRepo())
+	}
+	return repos, nil
+}
+
+// decodeJSONArrays decodes the back-to-back JSON arrays that
+// "gh api --paginate" prints, one per page, into a single slice.
+func decodeJSONArrays[T any](in []byte) ([]T, error) {
+	dec := json.NewDecoder(bytes.NewReader(in))
+	var all []T
+	for {
+		var page []T
+		if err := dec.Decode(&page); err == io.EOF {
+			return all, nil
+		} else if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+	}
+}
@@ -1,7 +1,20 @@
 package github
 
+import "time"
+
 // Repo represents a GitHub repository.
 type Repo struct {
-	Name   string `json:"name"`
-	SSHUrl string `json:"sshUrl"`
+	Name          string    `json:"name"`
+	Owner         string    `json:"owner"`
+	SSHUrl        string    `json:"sshUrl"`
+	HTTPSUrl      string    `json:"httpsUrl"`
+	Description   string    `json:"description"`
+	Visibility    string    `json:"visibility"` // "public", "private" or "internal"
+	IsFork        bool      `json:"isFork"`
+	IsArchived    bool      `json:"isArchived"`
+	DefaultBranch string    `json:"defaultBranch"`
+	Language      string    `json:"language"` // primary language, may be empty
+	Topics        []string  `json:"topics"`
+	DiskUsage     int       `json:"diskUsage"` // in kilobytes
+	PushedAt      time.Time `json:"pushedAt"`
 }
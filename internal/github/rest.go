@@ -0,0 +1,298 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the root of the public GitHub REST API.
+const DefaultBaseURL = "https://api.github.com"
+
+// APIError is a non-successful response from the GitHub REST API.
+type APIError struct {
+	StatusCode int
+	URL        string
+	Message    string // "message" field of the response body, if any
+}
+
+func (e *APIError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("GitHub API request failed (%s): %d %s", e.URL, e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("GitHub API request failed (%s): %d %s", e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// RateLimitError is returned when the API rate limit is exhausted and the
+// reset is further away than the client is willing to wait.
+type RateLimitError struct {
+	Reset time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("GitHub API rate limit exceeded; resets at %s", e.Reset.Format(time.Kitchen))
+}
+
+// RESTClient is the Client that talks to the GitHub REST API over HTTP.
+type RESTClient struct {
+	// BaseURL is the API root, DefaultBaseURL unless pointed at GitHub
+	// Enterprise or a test server.
+	BaseURL string
+	// Token authenticates requests. Without it, only public data is
+	// visible and the rate limit is much lower.
+	Token string
+	// HTTPClient performs the requests.
+	HTTPClient *http.Client
+	// MaxRateLimitWait is the longest the client sleeps for a rate limit
+	// to reset before giving up with a *RateLimitError.
+	MaxRateLimitWait time.Duration
+}
+
+// NewRESTClient returns a RESTClient for api.github.com using token.
+func NewRESTClient(token string) *RESTClient {
+	return &RESTClient{
+		BaseURL:          DefaultBaseURL,
+		Token:            token,
+		HTTPClient:       &http.Client{Timeout: 30 * time.Second},
+		MaxRateLimitWait: time.Minute,
+	}
+}
+
+// restRepo is a repository as returned by the REST API.
+type restRepo struct {
+	Name  string `json:"name"`
+	Owner struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+	SSHURL        string    `json:"ssh_url"`
+	CloneURL      string    `json:"clone_url"`
+	Description   string    `json:"description"`
+	Visibility    string    `json:"visibility"`
+	Fork          bool      `json:"fork"`
+	Archived      bool      `json:"archived"`
+	DefaultBranch string    `json:"default_branch"`
+	Language      string    `json:"language"`
+	Topics        []string  `json:"topics"`
+	Size          int       `json:"size"`
+	PushedAt      time.Time `json:"pushed_at"`
+}
+
+func (r restRepo) toRepo() Repo {
+	return Repo{
+		Name:          r.Name,
+		Owner:         r.Owner.Login,
+		SSHUrl:        r.SSHURL,
+		HTTPSUrl:      r.CloneURL,
+		Description:   r.Description,
+		Visibility:    strings.ToLower(r.Visibility),
+		IsFork:        r.Fork,
+		IsArchived:    r.Archived,
+		DefaultBranch: r.DefaultBranch,
+		Language:      r.Language,
+		Topics:        r.Topics,
+		DiskUsage:     r.Size,
+		PushedAt:      r.PushedAt,
+	}
+}
+
+// ListSelfRepos lists GET /user/repos, limited to repos the user owns.
+func (c *RESTClient) ListSelfRepos(ctx context.Context, limit int) ([]Repo, error) {
+	return c.listRepos(ctx, "/user/repos?affiliation=owner", limit)
+}
+
+// ListPublicRepos lists GET /users/{username}/repos.
+func (c *RESTClient) ListPublicRepos(ctx context.Context, username string, limit int) ([]Repo, error) {
+	return c.listRepos(ctx, "/users/"+url.PathEscape(username)+"/repos?type=owner", limit)
+}
+
+// ListOrgRepos lists GET /orgs/{org}/repos.
+func (c *RESTClient) ListOrgRepos(ctx context.Context, orgLogin string, limit int) ([]Repo, error) {
+	return c.listRepos(ctx, "/orgs/"+url.PathEscape(orgLogin)+"/repos", limit)
+}
+
+// ListUserOrgs lists GET /user/orgs.
+func (c *RESTClient) ListUserOrgs(ctx context.Context) ([]Org, error) {
+	var orgs []Org
+	err := c.paginate(ctx, "/user/orgs", func(body []byte) (bool, error) {
+		var page []Org
+		if err := json.Unmarshal(body, &page); err != nil {
+			return false, fmt.Errorf("failed to parse orgs: %w", err)
+		}
+		orgs = append(orgs, page...)
+		return true, nil
+	})
+	return orgs, err
+}
+
+func (c *RESTClient) listRepos(ctx context.Context, path string, limit int) ([]Repo, error) {
+	var repos []Repo
+	err := c.paginate(ctx, path, func(body []byte) (bool, error) {
+		var page []restRepo
+		if err := json.Unmarshal(body, &page); err != nil {
+			return false, fmt.Errorf("failed to parse repo list JSON: %w", err)
+		}
+		for _, r := range page {
+			if limit > 0 && len(repos) == limit {
+				return false, nil
+			}
+			repos = append(repos, r.toRepo())
+		}
+		return true, nil
+	})
+	return repos, err
+}
+
+// paginate GETs path and every following page from the Link header,
+// handing each body to page until it returns false or there are no more.
+func (c *RESTClient) paginate(ctx context.Context, path string, page func([]byte) (bool, error)) error {
+	next, err := c.pageURL(path)
+	if err != nil {
+		return err
+	}
+	for next != "" {
+		body, header, err := c.get(ctx, next)
+		if err != nil {
+			return err
+		}
+		more, err := page(body)
+		if err != nil || !more {
+			return err
+		}
+		next = nextLink(header.Get("Link"))
+	}
+	return nil
+}
+
+// pageURL resolves path against BaseURL, asking for the largest page size.
+func (c *RESTClient) pageURL(path string) (string, error) {
+	base := c.BaseURL
+	if base == "" {
+		base = DefaultBaseURL
+	}
+	u, err := url.Parse(strings.TrimSuffix(base, "/") + path)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set("per_page", "100")
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// maxRateLimitRetries is how many times get retries a rate-limited request
+// before giving up with a *RateLimitError.
+const maxRateLimitRetries = 3
+
+// minRateLimitWait is the shortest wait before retrying a rate-limited
+// request, so a Retry-After of 0 or a reset in the past cannot busy-loop.
+var minRateLimitWait = time.Second
+
+// get performs a GET request, waiting out rate limits up to MaxRateLimitWait
+// at most maxRateLimitRetries times.
+func (c *RESTClient) get(ctx context.Context, rawURL string) ([]byte, http.Header, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		req.Header.Set("Accept", "application/vnd.github+json")
+		req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+		if c.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.Token)
+		}
+
+		httpClient := c.HTTPClient
+		if httpClient == nil {
+			httpClient = http.DefaultClient
+		}
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return nil, nil, err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return body, resp.Header, nil
+		}
+
+		if wait, limited := rateLimitWait(resp, time.Now()); limited {
+			wait = max(wait, minRateLimitWait)
+			if wait > c.MaxRateLimitWait || attempt == maxRateLimitRetries {
+				return nil, nil, &RateLimitError{Reset: time.Now().Add(wait)}
+			}
+			if err := sleepCtx(ctx, wait); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+
+		apiErr := &APIError{StatusCode: resp.StatusCode, URL: rawURL}
+		var payload struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(body, &payload) == nil {
+			apiErr.Message = payload.Message
+		}
+		return nil, nil, apiErr
+	}
+}
+
+// rateLimitWait reports whether resp is a rate-limit rejection and, if so,
+// how long to wait before retrying. Both the primary limit
+// (X-RateLimit-Remaining: 0) and secondary limits (Retry-After) are handled.
+func rateLimitWait(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	if s := resp.Header.Get("Retry-After"); s != "" {
+		if secs, err := strconv.Atoi(s); err == nil {
+			return time.Duration(secs) * time.Second, true
+		}
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+		if err != nil {
+			return time.Minute, true
+		}
+		wait := time.Unix(reset, 0).Sub(now)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait + time.Second, true
+	}
+	return 0, false
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+var linkNextRE = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// nextLink extracts the rel="next" URL from a Link header.
+func nextLink(header string) string {
+	for _, part := range strings.Split(header, ",") {
+		if m := linkNextRE.FindStringSubmatch(part); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,275 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testClient returns a RESTClient talking to a test server running h.
+func testClient(t *testing.T, h http.HandlerFunc) *RESTClient {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	c := NewRESTClient("token")
+	c.BaseURL = srv.URL
+	c.HTTPClient = srv.Client()
+	return c
+}
+
+// shortBackoff makes rate-limit retries fast for the duration of a test.
+func shortBackoff(t *testing.T) {
+	t.Helper()
+	old := minRateLimitWait
+	minRateLimitWait = time.Millisecond
+	t.Cleanup(func() { minRateLimitWait = old })
+}
+
+// reposPage renders n repositories named prefix0, prefix1, ... as JSON.
+func reposPage(prefix string, n int) string {
+	items := make([]string, n)
+	for i := range items {
+		items[i] = fmt.Sprintf(`{"name":"%s%d","owner":{"login":"o"},"ssh_url":"git@github.com:o/%s%d.git"}`, prefix, i, prefix, i)
+	}
+	return "[" + strings.Join(items, ",") + "]"
+}
+
+func TestListReposPagination(t *testing.T) {
+	var requests atomic.Int32
+	var c *RESTClient
+	c = testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if got := r.Header.Get("Authorization"); got != "Bearer token" {
+			t.Errorf("Authorization = %q", got)
+		}
+		if got := r.URL.Query().Get("per_page"); got != "100" {
+			t.Errorf("per_page = %q", got)
+		}
+		switch page := r.URL.Query().Get("page"); page {
+		case "":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/orgs/acme/repos?per_page=100&page=2>; rel="next", <%s/orgs/acme/repos?per_page=100&page=3>; rel="last"`, c.BaseURL, c.BaseURL))
+			fmt.Fprint(w, reposPage("a", 2))
+		case "2":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/orgs/acme/repos?per_page=100&page=1>; rel="prev", <%s/orgs/acme/repos?per_page=100&page=3>; rel="next"`, c.BaseURL, c.BaseURL))
+			fmt.Fprint(w, reposPage("b", 2))
+		case "3":
+			fmt.Fprint(w, reposPage("c", 1))
+		default:
+			t.Errorf("unexpected page %q", page)
+		}
+	})
+
+	repos, err := c.ListOrgRepos(context.Background(), "acme", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, r := range repos {
+		names = append(names, r.Name)
+	}
+	if got, want := strings.Join(names, ","), "a0,a1,b0,b1,c0"; got != want {
+		t.Errorf("names = %s, want %s", got, want)
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("made %d requests, want 3", n)
+	}
+}
+
+func TestListReposLimit(t *testing.T) {
+	var requests atomic.Int32
+	var c *RESTClient
+	c = testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Link", fmt.Sprintf(`<%s/user/repos?page=2>; rel="next"`, c.BaseURL))
+		fmt.Fprint(w, reposPage("r", 5))
+	})
+
+	for _, tt := range []struct {
+		limit, want, requests int
+	}{
+		{limit: 3, want: 3, requests: 1},
+		{limit: 5, want: 5, requests: 2},
+		{limit: 7, want: 7, requests: 2},
+	} {
+		requests.Store(0)
+		repos, err := c.ListSelfRepos(context.Background(), tt.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(repos) != tt.want {
+			t.Errorf("limit %d: got %d repos, want %d", tt.limit, len(repos), tt.want)
+		}
+		if n := int(requests.Load()); n != tt.requests {
+			t.Errorf("limit %d: made %d requests, want %d", tt.limit, n, tt.requests)
+		}
+	}
+}
+
+func TestRateLimitWait(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	tests := []struct {
+		name    string
+		status  int
+		header  map[string]string
+		wait    time.Duration
+		limited bool
+	}{
+		{"not found", 404, nil, 0, false},
+		{"forbidden without limit headers", 403, nil, 0, false},
+		{"secondary", 403, map[string]string{"Retry-After": "30"}, 30 * time.Second, true},
+		{"secondary 429", 429, map[string]string{"Retry-After": "5"}, 5 * time.Second, true},
+		{"secondary zero", 429, map[string]string{"Retry-After": "0"}, 0, true},
+		{"primary", 403, map[string]string{
+			"X-RateLimit-Remaining": "0",
+			"X-RateLimit-Reset":     strconv.FormatInt(now.Add(10*time.Second).Unix(), 10),
+		}, 11 * time.Second, true},
+		{"primary reset passed", 403, map[string]string{
+			"X-RateLimit-Remaining": "0",
+			"X-RateLimit-Reset":     strconv.FormatInt(now.Add(-time.Minute).Unix(), 10),
+		}, time.Second, true},
+		{"primary without reset", 403, map[string]string{"X-RateLimit-Remaining": "0"}, time.Minute, true},
+		{"primary with requests left", 403, map[string]string{"X-RateLimit-Remaining": "12"}, 0, false},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+		for k, v := range tt.header {
+			resp.Header.Set(k, v)
+		}
+		wait, limited := rateLimitWait(resp, now)
+		if wait != tt.wait || limited != tt.limited {
+			t.Errorf("%s: rateLimitWait = %v, %v; want %v, %v", tt.name, wait, limited, tt.wait, tt.limited)
+		}
+	}
+}
+
+func TestGetWaitsOutRateLimits(t *testing.T) {
+	shortBackoff(t)
+	for _, tt := range []struct {
+		name   string
+		header map[string]string
+	}{
+		{"secondary", map[string]string{"Retry-After": "0"}},
+		{"primary", map[string]string{
+			"X-RateLimit-Remaining": "0",
+			"X-RateLimit-Reset":     strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10),
+		}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if requests.Add(1) == 1 {
+					for k, v := range tt.header {
+						w.Header().Set(k, v)
+					}
+					w.WriteHeader(http.StatusForbidden)
+					fmt.Fprint(w, `{"message":"API rate limit exceeded"}`)
+					return
+				}
+				fmt.Fprint(w, `[{"login":"acme"}]`)
+			})
+
+			orgs, err := c.ListUserOrgs(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(orgs) != 1 || orgs[0].Login != "acme" {
+				t.Errorf("orgs = %+v", orgs)
+			}
+			if n := requests.Load(); n != 2 {
+				t.Errorf("made %d requests, want 2", n)
+			}
+		})
+	}
+}
+
+func TestGetRateLimitError(t *testing.T) {
+	shortBackoff(t)
+
+	t.Run("wait too long", func(t *testing.T) {
+		var requests atomic.Int32
+		c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+		})
+		c.MaxRateLimitWait = time.Second
+
+		_, err := c.ListUserOrgs(context.Background())
+		var rle *RateLimitError
+		if !errors.As(err, &rle) {
+			t.Fatalf("err = %v, want a *RateLimitError", err)
+		}
+		if until := time.Until(rle.Reset); until < 59*time.Minute {
+			t.Errorf("Reset is %v away, want about an hour", until)
+		}
+		if n := requests.Load(); n != 1 {
+			t.Errorf("made %d requests, want 1", n)
+		}
+	})
+
+	t.Run("retries exhausted", func(t *testing.T) {
+		var requests atomic.Int32
+		c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		})
+
+		_, err := c.ListUserOrgs(context.Background())
+		var rle *RateLimitError
+		if !errors.As(err, &rle) {
+			t.Fatalf("err = %v, want a *RateLimitError", err)
+		}
+		if n := requests.Load(); n != maxRateLimitRetries+1 {
+			t.Errorf("made %d requests, want %d", n, maxRateLimitRetries+1)
+		}
+	})
+}
+
+func TestAPIError(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		msg    string
+	}{
+		{"message", 404, `{"message":"Not Found","documentation_url":"https://docs.github.com"}`, "Not Found"},
+		{"bad credentials", 401, `{"message":"Bad credentials"}`, "Bad credentials"},
+		{"no message", 500, `{}`, ""},
+		{"not json", 502, `<html>Bad Gateway</html>`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			})
+
+			_, err := c.ListOrgRepos(context.Background(), "acme", 0)
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("err = %v, want an *APIError", err)
+			}
+			if apiErr.StatusCode != tt.status || apiErr.Message != tt.msg {
+				t.Errorf("APIError = %d %q, want %d %q", apiErr.StatusCode, apiErr.Message, tt.status, tt.msg)
+			}
+			if !strings.Contains(apiErr.URL, "/orgs/acme/repos") {
+				t.Errorf("URL = %q", apiErr.URL)
+			}
+			want := tt.msg
+			if want == "" {
+				want = http.StatusText(tt.status)
+			}
+			if !strings.HasSuffix(err.Error(), fmt.Sprintf("%d %s", tt.status, want)) {
+				t.Errorf("Error() = %q", err.Error())
+			}
+		})
+	}
+}
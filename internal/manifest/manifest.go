@@ -0,0 +1,207 @@
+// Package manifest reads and writes workspace manifests: declarative
+// descriptions of the repositories that belong in a workspace and where
+// each one is checked out.
+//
+// A manifest looks like this:
+//
+//	version: 1
+//	root: ~/src
+//	layout: owner
+//	hooks:
+//	  - git config core.autocrlf input
+//	sources:
+//	  - org: my-org
+//	    match: ["svc-*"]
+//	    exclude: ["*-legacy"]
+//	    hooks: ["go mod download"]
+//	repos:
+//	  - name: my-org/web
+//	    path: frontend/web
+//	    branch: develop
+//	    hooks: ["npm ci"]
+//	  - url: git@gitlab.com:me/dotfiles.git
+//	    remotes:
+//	      upstream: git@gitlab.com:them/dotfiles.git
+//
+// Sources select repositories from a GitHub listing; repos name single
+// repositories, or override the settings of a repository a source selected.
+package manifest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Version is the manifest format version this package reads and writes.
+const Version = 1
+
+// Manifest describes a workspace.
+type Manifest struct {
+	Version int `yaml:"version" json:"version"`
+	// Root is the directory the workspace lives in. A leading "~" is the
+	// home directory; a relative root is relative to the manifest file.
+	// Empty leaves the choice to the caller.
+	Root string `yaml:"root,omitempty" json:"root,omitempty"`
+	// Layout places repositories that have no explicit path; see
+	// ghops.ParseLayout. Empty leaves the choice to the caller.
+	Layout string `yaml:"layout,omitempty" json:"layout,omitempty"`
+	// Hooks run in every repository after it is cloned.
+	Hooks   []string `yaml:"hooks,omitempty" json:"hooks,omitempty"`
+	Sources []Source `yaml:"sources,omitempty" json:"sources,omitempty"`
+	Repos   []Repo   `yaml:"repos,omitempty" json:"repos,omitempty"`
+
+	// dir is the directory of the file the manifest was loaded from.
+	dir string
+}
+
+// Source selects repositories from the listing of the authenticated user
+// (Self), a user or an organization. Exactly one of them is set.
+type Source struct {
+	Self bool   `yaml:"self,omitempty" json:"self,omitempty"`
+	User string `yaml:"user,omitempty" json:"user,omitempty"`
+	Org  string `yaml:"org,omitempty" json:"org,omitempty"`
+	// Match keeps only repositories whose name matches one of these
+	// globs; empty keeps all. Exclude drops those matching any.
+	Match   []string `yaml:"match,omitempty" json:"match,omitempty"`
+	Exclude []string `yaml:"exclude,omitempty" json:"exclude,omitempty"`
+	// Path is the directory, relative to the root, that the source's
+	// repositories are laid out in. Empty is the root itself.
+	Path string `yaml:"path,omitempty" json:"path,omitempty"`
+	// Branch is checked out in each repository; empty keeps the default.
+	Branch string `yaml:"branch,omitempty" json:"branch,omitempty"`
+	// Hooks run in each repository after it is cloned, after the
+	// manifest's own hooks.
+	Hooks []string `yaml:"hooks,omitempty" json:"hooks,omitempty"`
+}
+
+// Repo is a single repository. At least one of Name and URL is set: Name
+// ("owner/name") refers to a GitHub repository, and URL clones from
+// anywhere.
+type Repo struct {
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+	URL  string `yaml:"url,omitempty" json:"url,omitempty"`
+	// Path is the checkout's directory relative to the root; empty
+	// places it with the layout.
+	Path   string `yaml:"path,omitempty" json:"path,omitempty"`
+	Branch string `yaml:"branch,omitempty" json:"branch,omitempty"`
+	// Remotes are added to the checkout besides origin, by name.
+	Remotes map[string]string `yaml:"remotes,omitempty" json:"remotes,omitempty"`
+	// Hooks run after the repository is cloned, after those of the
+	// manifest and of a source that selected it.
+	Hooks []string `yaml:"hooks,omitempty" json:"hooks,omitempty"`
+}
+
+// Load reads the manifest in the file at path, or from stdin if path is
+// "-". YAML and JSON are both accepted.
+func Load(path string) (*Manifest, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read manifest: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+	m, err := Read(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+	if path != "-" {
+		m.dir = filepath.Dir(path)
+	}
+	return m, nil
+}
+
+// Read decodes and validates a manifest. Relative roots are taken to be
+// relative to the current directory.
+func Read(r io.Reader) (*Manifest, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&m); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Validate checks that m is well formed. A missing version is taken to be
+// Version.
+func (m *Manifest) Validate() error {
+	if m.Version == 0 {
+		m.Version = Version
+	}
+	if m.Version != Version {
+		return fmt.Errorf("unsupported version %d (want %d)", m.Version, Version)
+	}
+	for i, s := range m.Sources {
+		n := 0
+		for _, set := range []bool{s.Self, s.User != "", s.Org != ""} {
+			if set {
+				n++
+			}
+		}
+		if n != 1 {
+			return fmt.Errorf("sources[%d]: set exactly one of self, user and org", i)
+		}
+		if err := checkPath(s.Path); err != nil {
+			return fmt.Errorf("sources[%d]: %w", i, err)
+		}
+	}
+	for i, r := range m.Repos {
+		if r.Name == "" && r.URL == "" {
+			return fmt.Errorf("repos[%d]: set name or url", i)
+		}
+		if r.Name != "" {
+			if owner, name, ok := strings.Cut(r.Name, "/"); !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+				return fmt.Errorf("repos[%d]: name %q is not of the form owner/name", i, r.Name)
+			}
+		}
+		if err := checkPath(r.Path); err != nil {
+			return fmt.Errorf("repos[%d]: %w", i, err)
+		}
+		if _, ok := r.Remotes["origin"]; ok {
+			return fmt.Errorf("repos[%d]: origin is set by name or url, not remotes", i)
+		}
+	}
+	return nil
+}
+
+// checkPath rejects paths that would leave the root.
+func checkPath(p string) error {
+	if p != "" && !filepath.IsLocal(filepath.FromSlash(p)) {
+		return fmt.Errorf("path %q must be relative and inside the root", p)
+	}
+	return nil
+}
+
+// RootDir returns m.Root with "~" expanded and, when m was loaded from a
+// file, relative to that file's directory. It returns "" if m has no root.
+func (m *Manifest) RootDir() string {
+	root := m.Root
+	switch {
+	case root == "":
+		return ""
+	case root == "~" || strings.HasPrefix(root, "~/"):
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, root[1:])
+		}
+	case !filepath.IsAbs(root) && m.dir != "":
+		return filepath.Join(m.dir, root)
+	}
+	return root
+}
@@ -0,0 +1,122 @@
+// Package output writes command results as a table or in a
+// machine-readable format.
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format is an output format.
+type Format string
+
+const (
+	Table Format = "table" // aligned columns for people
+	TSV   Format = "tsv"   // tab-separated columns with a header line
+	JSON  Format = "json"
+	YAML  Format = "yaml"
+)
+
+// Formats lists the valid formats.
+var Formats = []Format{Table, JSON, YAML, TSV}
+
+// ParseFormat validates a format name; "" means Table.
+func ParseFormat(s string) (Format, error) {
+	if s == "" {
+		return Table, nil
+	}
+	for _, f := range Formats {
+		if string(f) == strings.ToLower(s) {
+			return f, nil
+		}
+	}
+	names := make([]string, len(Formats))
+	for i, f := range Formats {
+		names[i] = string(f)
+	}
+	return "", fmt.Errorf("invalid output format %q (want one of %s)", s, strings.Join(names, ", "))
+}
+
+// Human reports whether f is meant for people rather than other programs.
+// Commands only print progress and summaries alongside human output.
+func (f Format) Human() bool {
+	return f == Table
+}
+
+// Rows is the tabular form of a result: a header and one row per item.
+type Rows struct {
+	Columns []string
+	Rows    [][]string
+}
+
+// Write writes data to w in format f. JSON and YAML encode data, using its
+// json struct tags for both; Table and TSV print rows.
+func Write(w io.Writer, f Format, data any, rows Rows) error {
+	switch f {
+	case JSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(data)
+	case YAML:
+		return writeYAML(w, data)
+	case TSV:
+		fmt.Fprintln(w, strings.Join(rows.Columns, "\t"))
+		for _, r := range rows.Rows {
+			cells := make([]string, len(r))
+			for i, c := range r {
+				cells[i] = tsvEscape(c)
+			}
+			if _, err := fmt.Fprintln(w, strings.Join(cells, "\t")); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(rows.Columns, "\t"))
+		for _, r := range rows.Rows {
+			fmt.Fprintln(tw, strings.Join(r, "\t"))
+		}
+		return tw.Flush()
+	}
+}
+
+// writeYAML encodes data as YAML with the same keys, in the same order,
+// as its JSON encoding.
+func writeYAML(w io.Writer, data any) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	var node yaml.Node
+	if err := yaml.NewDecoder(bytes.NewReader(b)).Decode(&node); err != nil {
+		return err
+	}
+	clearStyle(&node)
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// clearStyle drops the flow style YAML gives decoded JSON, so the output
+// is block-style YAML.
+func clearStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		clearStyle(c)
+	}
+}
+
+// tsvEscape escapes the characters that would break a TSV row.
+func tsvEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`).Replace(s)
+}
@@ -0,0 +1,189 @@
+// Package remoteurl parses git remote URLs into their host, owner and
+// repository, and renders them back in any protocol.
+//
+// It understands the forms git accepts for remotes:
+//
+//	https://github.com/owner/repo.git
+//	http://ghe.example.com/owner/repo
+//	ssh://git@github.com:22/owner/repo.git
+//	git+ssh://git@github.com/owner/repo
+//	git://github.com/owner/repo.git
+//	git@github.com:owner/repo.git  (scp-like)
+//	github-work:owner/repo         (scp-like, SSH host alias)
+//
+// Rewrites configured with url.<base>.insteadOf are applied by git, not
+// here; pass the URL reported by "git remote get-url", which has them
+// applied.
+package remoteurl
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Protocol is how a remote is reached.
+type Protocol string
+
+const (
+	SSH   Protocol = "ssh"
+	HTTPS Protocol = "https"
+	HTTP  Protocol = "http"
+	Git   Protocol = "git"
+)
+
+// ParseProtocol validates a protocol name. "ssh+git" and "git+ssh" are
+// accepted as SSH.
+func ParseProtocol(s string) (Protocol, error) {
+	switch p := Protocol(strings.ToLower(s)); p {
+	case SSH, HTTPS, HTTP, Git:
+		return p, nil
+	case "git+ssh", "ssh+git":
+		return SSH, nil
+	}
+	return "", fmt.Errorf("unknown protocol %q (want ssh, https, http or git)", s)
+}
+
+// URL is a parsed remote URL.
+type URL struct {
+	Protocol Protocol
+	User     string // e.g. "git"; empty if the URL names none
+	Host     string // host name or SSH alias, without the port
+	Port     string // empty for the protocol's default
+	// Owner is the path up to the repository: a user or organization,
+	// or for hosts with nested groups, e.g. "group/subgroup".
+	Owner string
+	Repo  string // repository name without a ".git" suffix
+
+	scp bool // written as [user@]host:path
+}
+
+// Parse parses a remote URL. It fails if raw has no host or its path does
+// not end in an owner and a repository.
+func Parse(raw string) (URL, error) {
+	s := strings.TrimSpace(raw)
+	if s == "" {
+		return URL{}, fmt.Errorf("empty remote URL")
+	}
+
+	var (
+		u    URL
+		path string
+		err  error
+	)
+	if scheme, rest, ok := strings.Cut(s, "://"); ok {
+		u, path, err = parseURL(scheme, rest, s)
+		if err != nil {
+			return URL{}, fmt.Errorf("invalid remote URL %q: %w", raw, err)
+		}
+	} else {
+		var ok bool
+		u, path, ok = parseSCP(s)
+		if !ok {
+			return URL{}, fmt.Errorf("invalid remote URL %q: not a URL or [user@]host:path", raw)
+		}
+	}
+	if u.Host == "" {
+		return URL{}, fmt.Errorf("invalid remote URL %q: no host", raw)
+	}
+
+	segs := strings.FieldsFunc(path, func(r rune) bool { return r == '/' })
+	if len(segs) < 2 {
+		return URL{}, fmt.Errorf("invalid remote URL %q: want a path of the form owner/repo", raw)
+	}
+	u.Owner = strings.Join(segs[:len(segs)-1], "/")
+	u.Repo = strings.TrimSuffix(segs[len(segs)-1], ".git")
+	if u.Repo == "" {
+		return URL{}, fmt.Errorf("invalid remote URL %q: empty repository name", raw)
+	}
+	return u, nil
+}
+
+// parseURL parses a URL with a scheme, returning everything but the owner
+// and repository, and the path they are in.
+func parseURL(scheme, rest, s string) (URL, string, error) {
+	p, err := ParseProtocol(scheme)
+	if err != nil {
+		return URL{}, "", err
+	}
+	pu, err := url.Parse(s)
+	if err != nil {
+		// git also accepts ssh://[user@]host:path, which mixes in the
+		// scp-like syntax and is not a valid URL.
+		if p == SSH {
+			if u, path, ok := parseSCP(rest); ok {
+				u.scp = false
+				return u, path, nil
+			}
+		}
+		return URL{}, "", err
+	}
+	u := URL{Protocol: p, Host: pu.Hostname(), Port: pu.Port(), User: pu.User.Username()}
+	return u, pu.Path, nil
+}
+
+// parseSCP parses the scp-like syntax [user@]host:path. Like git, it only
+// applies when the colon comes before any slash.
+func parseSCP(s string) (URL, string, bool) {
+	colon := strings.Index(s, ":")
+	if colon <= 0 || strings.Contains(s[:colon], "/") {
+		return URL{}, "", false
+	}
+	u := URL{Protocol: SSH, scp: true}
+	hostPart := s[:colon]
+	if user, host, ok := strings.Cut(hostPart, "@"); ok {
+		u.User, hostPart = user, host
+	}
+	u.Host = strings.Trim(hostPart, "[]")
+	return u, s[colon+1:], true
+}
+
+// FullName returns "owner/repo".
+func (u URL) FullName() string {
+	return u.Owner + "/" + u.Repo
+}
+
+// String renders u in its own protocol. Scp-like URLs stay scp-like, and
+// credentials in HTTP(S) URLs are left out.
+func (u URL) String() string {
+	path := u.FullName() + ".git"
+	switch {
+	case u.Protocol == SSH && u.scp:
+		return userPrefix(u.User) + u.Host + ":" + path
+	case u.Protocol == SSH:
+		return "ssh://" + userPrefix(u.User) + hostPort(u.Host, u.Port) + "/" + path
+	default:
+		return string(u.Protocol) + "://" + hostPort(u.Host, u.Port) + "/" + path
+	}
+}
+
+// As returns u converted to protocol p. Converting to SSH gives the
+// scp-like form with user "git" unless u is already SSH; converting away
+// from a protocol drops its user and port.
+func (u URL) As(p Protocol) URL {
+	if p == u.Protocol {
+		return u
+	}
+	out := URL{Protocol: p, Host: u.Host, Owner: u.Owner, Repo: u.Repo}
+	if p == SSH {
+		out.User, out.scp = "git", true
+	}
+	return out
+}
+
+func userPrefix(user string) string {
+	if user == "" {
+		return ""
+	}
+	return user + "@"
+}
+
+func hostPort(host, port string) string {
+	if strings.Contains(host, ":") { // IPv6 literal
+		host = "[" + host + "]"
+	}
+	if port == "" {
+		return host
+	}
+	return host + ":" + port
+}
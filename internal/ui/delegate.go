@@ -0,0 +1,51 @@
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+)
+
+// selection is the set of selected repos in the repo list, keyed by
+// repoItem.key. It is shared between the model and repoDelegate, so it
+// must be cleared in place rather than replaced.
+type selection map[string]bool
+
+// repoDelegate renders repo items like list.DefaultDelegate, with a
+// checkbox in front of each item showing whether it is selected.
+type repoDelegate struct {
+	list.DefaultDelegate
+	selected selection
+}
+
+func newRepoDelegate(selected selection) repoDelegate {
+	return repoDelegate{DefaultDelegate: list.NewDefaultDelegate(), selected: selected}
+}
+
+func (d repoDelegate) Render(w io.Writer, m list.Model, index int, item list.Item) {
+	ri, ok := item.(repoItem)
+	if !ok {
+		d.DefaultDelegate.Render(w, m, index, item)
+		return
+	}
+
+	box := "[ ] "
+	if d.selected[ri.key()] {
+		box = CheckMarkStyle.Render("[x]") + " "
+	}
+
+	var buf bytes.Buffer
+	d.DefaultDelegate.Render(&buf, m, index, item)
+	lines := strings.Split(buf.String(), "\n")
+	for i := range lines {
+		if i == 0 {
+			lines[i] = box + lines[i]
+		} else {
+			lines[i] = "    " + lines[i]
+		}
+	}
+	fmt.Fprint(w, strings.Join(lines, "\n"))
+}
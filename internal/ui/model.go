@@ -2,14 +2,21 @@ package ui
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/progress"
 	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	zone "github.com/lrstanley/bubblezone"
+	"github.com/sanurb/ghpm/internal/config"
+	"github.com/sanurb/ghpm/internal/ghops"
+	"github.com/sanurb/ghpm/internal/git"
 	"github.com/sanurb/ghpm/internal/github"
 )
 
@@ -23,10 +30,12 @@ const (
 	StateDone
 	StateInput
 	StateDownloading
+	StateRunning
+	StateStatus
 )
 
 type (
-	reposMsg []github.Repo
+	reposMsg ghops.Listing
 	orgsMsg  []github.Org
 	errMsg   struct{ err error }
 )
@@ -34,19 +43,46 @@ type (
 func (e errMsg) Error() string { return e.err.Error() }
 
 type repoItem struct {
-	name   string
-	sshUrl string
+	repo github.Repo
 }
 
-func (r repoItem) Title() string       { return r.name }
-func (r repoItem) Description() string { return r.sshUrl }
-func (r repoItem) FilterValue() string { return r.name }
+func (r repoItem) Title() string { return r.repo.Name }
+
+// key identifies the repo in a selection.
+func (r repoItem) key() string { return r.repo.Owner + "/" + r.repo.Name }
+
+// Description shows the repo's language, fork/archived flags and
+// description, falling back to its SSH URL.
+func (r repoItem) Description() string {
+	var parts []string
+	if r.repo.Language != "" {
+		parts = append(parts, r.repo.Language)
+	}
+	if r.repo.IsFork {
+		parts = append(parts, "fork")
+	}
+	if r.repo.IsArchived {
+		parts = append(parts, "archived")
+	}
+	if r.repo.Description != "" {
+		parts = append(parts, r.repo.Description)
+	}
+	if len(parts) == 0 {
+		return r.repo.SSHUrl
+	}
+	return strings.Join(parts, " · ")
+}
+
+func (r repoItem) FilterValue() string { return r.repo.Name }
 
 // Key bindings
 type keyMap struct {
-	Quit     key.Binding
-	Help     key.Binding
-	CloneAll key.Binding
+	Quit          key.Binding
+	Help          key.Binding
+	CloneAll      key.Binding
+	Toggle        key.Binding
+	ToggleVisible key.Binding
+	Clone         key.Binding
 }
 
 func (k keyMap) ShortHelp() []key.Binding {
@@ -70,6 +106,18 @@ func defaultKeyMap() keyMap {
 			key.WithKeys("a"),
 			key.WithHelp("a", "clone all repos"),
 		),
+		Toggle: key.NewBinding(
+			key.WithKeys(" "),
+			key.WithHelp("space", "select"),
+		),
+		ToggleVisible: key.NewBinding(
+			key.WithKeys("v"),
+			key.WithHelp("v", "select/deselect shown"),
+		),
+		Clone: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "clone selected"),
+		),
 	}
 }
 
@@ -83,6 +131,7 @@ type TuiModel struct {
 
 	repoList list.Model
 	repos    []github.Repo
+	selected selection
 
 	operation   string
 	inputResult string
@@ -96,17 +145,43 @@ type TuiModel struct {
 	helpModel help.Model
 	keys      keyMap
 
-	progress       progress.Model
-	downloading    bool
-	downloadIndex  int
-	downloadTarget int
-	downloadRepos  []string
-	done           bool
-
-	width    int
-	height   int
-	showHelp bool
-	pageSize int
+	progress        progress.Model
+	downloading     bool
+	downloadIndex   int
+	downloadTarget  int
+	downloadRepos   []github.Repo
+	downloadResults []ghops.Result
+	lastCloned      string
+	cloneResults    chan clonedRepoMsg
+	done            bool
+
+	runTitle   string
+	runLog     string
+	runCount   int
+	runResults []ghops.Result
+	runErr     error
+	runDone    bool
+	runEvents  chan tea.Msg
+	runView    viewport.Model
+
+	statuses      []ghops.RepoStatus
+	statusLoading bool
+	statusSort    int // index into ghops.StatusSortKeys
+	statusFilter  int // index into statusFilters
+	statusTable   table.Model
+
+	width      int
+	height     int
+	showHelp   bool
+	pageSize   int
+	jobs       int                  // repositories processed in parallel
+	repoLimit  int                  // maximum repositories per listing, 0 for all
+	onConflict ghops.ConflictPolicy // what to do when a clone target exists
+	root       string               // directory repos are cloned into and searched for
+	layout     string               // where clones go under root, see ghops.ParseLayout
+	hooks      []config.Hook        // post-clone hooks
+	dryRun     bool                 // record mutating commands in plan instead of running them
+	plan       *git.Plan            // commands recorded by the last dry run
 }
 
 func NewTuiModel(perPage int) TuiModel {
@@ -118,15 +193,24 @@ func NewTuiModel(perPage int) TuiModel {
 		"Clone Public Repos",
 		"Clone Repos from an Org",
 		"Run Command in All Repos",
-		"Set SSH Remote",
+		"Convert Remotes",
+		"Push All Repos",
+		"Pull All Repos",
+		"Workspace Status",
 		"Exit",
 	}
 
-	repoList := list.New(nil, list.NewDefaultDelegate(), 50, 10)
+	keys := defaultKeyMap()
+	selected := selection{}
+	repoList := list.New(nil, newRepoDelegate(selected), 50, 10)
 	repoList.Title = "Repositories"
 	repoList.SetFilteringEnabled(true)
 	repoList.SetShowHelp(true)
-	repoList.SetShowStatusBar(false)
+	repoList.SetShowStatusBar(true)
+	repoList.SetStatusBarItemName("repo", "repos")
+	repoList.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{keys.Toggle, keys.ToggleVisible, keys.Clone, keys.CloneAll}
+	}
 	repoList.SetShowPagination(true)
 	repoList.Paginator.PerPage = perPage
 
@@ -141,13 +225,27 @@ func NewTuiModel(perPage int) TuiModel {
 		sp:          sp,
 		menuOptions: menu,
 		repoList:    repoList,
+		selected:    selected,
 		helpModel:   help.New(),
-		keys:        defaultKeyMap(),
+		keys:        keys,
 		progress:    p,
 		pageSize:    perPage,
+		jobs:        config.AppConfig.Jobs,
+		repoLimit:   config.AppConfig.RepoLimit,
+		onConflict:  ghops.ConflictPolicy(config.AppConfig.OnConflict),
+		root:        config.AppConfig.Root(),
+		layout:      config.AppConfig.Layout,
+		hooks:       config.AppConfig.Hooks,
 	}
 }
 
+// WithDryRun returns m with dry-run mode preset, e.g. from --dry-run. It
+// can still be toggled from the menu.
+func (m TuiModel) WithDryRun(on bool) TuiModel {
+	m.dryRun = on
+	return m
+}
+
 func (m TuiModel) Init() tea.Cmd {
 	return tea.Batch(m.sp.Tick)
 }
@@ -184,6 +282,12 @@ func (m TuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case StateDownloading:
 		newM, cmd := m.updateDownloading(msg)
 		return newM, cmd
+	case StateRunning:
+		newM, cmd := m.updateRunning(msg)
+		return newM, cmd
+	case StateStatus:
+		newM, cmd := m.updateStatus(msg)
+		return newM, cmd
 	case StateDone:
 		newM, cmd := m.updateDone(msg)
 		return newM, cmd
@@ -211,6 +315,10 @@ func (m TuiModel) View() string {
 		out = m.repoList.View()
 	case StateDownloading:
 		out = m.renderDownloading()
+	case StateRunning:
+		out = m.renderRunning()
+	case StateStatus:
+		out = m.renderStatus()
 	case StateDone:
 		out = m.message + "\nPress any key to return to menu."
 	default:
@@ -235,19 +343,106 @@ func (m TuiModel) renderWelcomeAndMenu() string {
 		menu += zone.Mark(lineID, cursor+option) + "\n"
 	}
 
-	return WelcomeBoxStyle.Render(welcome) + "\n\n" + menu
+	mode := "Dry run: off (d to toggle)"
+	if m.dryRun {
+		mode = ErrorStyle.Render("Dry run: on") + " (d to toggle) · commands are shown, not run"
+	}
+	return WelcomeBoxStyle.Render(welcome) + "\n\n" + menu + "\n" + mode + "\n"
 }
 
 func (m TuiModel) renderDownloading() string {
 	if m.done {
-		return DoneStyle.Render(fmt.Sprintf("Done! Cloned %d repos.\n", m.downloadTarget))
+		var b strings.Builder
+		b.WriteString(fmt.Sprintf("Done! %d repos: %s.\n", m.downloadTarget, ghops.Summary(m.downloadResults)))
+		for _, r := range m.downloadResults {
+			if r.Err != nil {
+				b.WriteString("\n" + ErrorStyle.Render("✗ "+r.Name+" ("+r.Status+")") + ": " + r.Err.Error())
+			}
+		}
+		return DoneStyle.Render(b.String()) + renderPlan(m.plan) + "\nPress any key to return to menu."
 	}
 	spin := m.sp.View()
 	bar := m.progress.View()
-	var repoName string
-	if m.downloadIndex < len(m.downloadRepos) {
-		repoName = m.downloadRepos[m.downloadIndex]
+	info := fmt.Sprintf("Cloning %d/%d repos", m.downloadIndex, m.downloadTarget)
+	if m.lastCloned != "" {
+		info += " · finished " + CurrentRepoStyle.Render(m.lastCloned)
 	}
-	info := "Cloning " + CurrentRepoStyle.Render(repoName)
 	return fmt.Sprintf("%s\n\n%s\n\nPress q/esc to exit\n", spin+" "+info, bar)
 }
+
+func (m TuiModel) renderRunning() string {
+	header := TitleStyle.Render(m.runTitle)
+	footer := "↑/↓ scroll · q/esc quit"
+	if m.runDone {
+		footer = "↑/↓ scroll · enter back to menu · q/esc quit"
+	} else {
+		header = m.sp.View() + " " + header + fmt.Sprintf(" (%d finished)", m.runCount)
+	}
+	return header + "\n\n" + m.runView.View() + "\n" + footer
+}
+
+// renderPlan lists the commands recorded by a dry run, or returns "" if
+// there was none.
+func renderPlan(plan *git.Plan) string {
+	if plan == nil {
+		return ""
+	}
+	steps := plan.Steps()
+	if len(steps) == 0 {
+		return "\n" + DoneMessageStyle.Render("Dry run: nothing would change.") + "\n"
+	}
+	var b strings.Builder
+	b.WriteString("\n" + DoneMessageStyle.Render("Dry run: nothing was changed. Commands that would run:") + "\n")
+	dir := ""
+	for i, s := range steps {
+		if i == 0 || s.Dir != dir {
+			dir = s.Dir
+			b.WriteString("  in " + CurrentRepoStyle.Render(dir) + ":\n")
+		}
+		b.WriteString("    " + s.String() + "\n")
+	}
+	return b.String()
+}
+
+// renderRepoResult renders one repo's result as it streams into the log.
+func renderRepoResult(r ghops.Result) string {
+	status := CheckMarkStyle.Render("✓")
+	if r.Err != nil {
+		status = ErrorStyle.Render("✗")
+	}
+	name := CurrentRepoStyle.Render(r.Name)
+	if r.Status != ghops.StatusOK && r.Err == nil {
+		name += " [" + r.Status + "]"
+	}
+	s := fmt.Sprintf("%s %s (%s)\n", status, name, r.Duration.Round(time.Millisecond))
+	for _, line := range strings.Split(strings.TrimRight(r.Output+r.Stderr, "\n"), "\n") {
+		if line != "" {
+			s += "    " + line + "\n"
+		}
+	}
+	if r.Err != nil {
+		s += "    " + ErrorStyle.Render(r.Err.Error()) + "\n"
+	}
+	return s
+}
+
+// renderRunSummary renders the pass/fail list shown once a batch finishes.
+func (m TuiModel) renderRunSummary() string {
+	if m.runErr != nil {
+		return "\n" + ErrorStyle.Render("Error: "+m.runErr.Error()) + "\n"
+	}
+	var passed, failed int
+	var b strings.Builder
+	for _, r := range m.runResults {
+		mark := CheckMarkStyle.Render("✓")
+		if r.Err != nil {
+			mark = ErrorStyle.Render("✗")
+			failed++
+		} else {
+			passed++
+		}
+		b.WriteString(fmt.Sprintf("  %s %s\n", mark, r.Name))
+	}
+	title := DoneMessageStyle.Render(fmt.Sprintf("Finished: %d passed, %d failed", passed, failed))
+	return "\n" + title + "\n" + b.String()
+}
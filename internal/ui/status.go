@@ -0,0 +1,146 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/sanurb/ghpm/internal/ghops"
+)
+
+// statusMsg carries the statuses of the local repos.
+type statusMsg []ghops.RepoStatus
+
+// statusFilters are the views the status screen cycles through.
+var statusFilters = []struct {
+	name   string
+	filter ghops.StatusFilter
+}{
+	{"all", ghops.StatusFilter{}},
+	{"unpushed work", ghops.StatusFilter{Unpushed: true}},
+	{"dirty", ghops.StatusFilter{Dirty: true}},
+	{"ahead", ghops.StatusFilter{Ahead: true}},
+	{"behind", ghops.StatusFilter{Behind: true}},
+	{"detached", ghops.StatusFilter{Detached: true}},
+	{"stashed", ghops.StatusFilter{Stashed: true}},
+}
+
+// loadStatusCmd reads the status of every repo under root.
+func loadStatusCmd(root string, jobs int) tea.Cmd {
+	return func() tea.Msg {
+		statuses, err := ghops.LocalStatus(context.Background(), root, ghops.DiscoverOptions{}, jobs)
+		if err != nil {
+			return errMsg{err}
+		}
+		return statusMsg(statuses)
+	}
+}
+
+// startStatus switches to the status screen and starts loading it.
+func (m TuiModel) startStatus() (TuiModel, tea.Cmd) {
+	m.state = StateStatus
+	m.statusLoading = true
+	return m, tea.Batch(m.sp.Tick, loadStatusCmd(m.root, m.jobs))
+}
+
+// =============== STATUS ===============
+func (m TuiModel) updateStatus(msg tea.Msg) (TuiModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case spinner.TickMsg:
+		if !m.statusLoading {
+			return m, nil
+		}
+		newSpin, cmd := m.sp.Update(msg)
+		m.sp = newSpin
+		return m, cmd
+
+	case statusMsg:
+		m.statusLoading = false
+		m.statuses = msg
+		m.refreshStatusTable()
+		return m, nil
+
+	case errMsg:
+		m.message = fmt.Sprintf("Error reading repository status: %v", msg.err)
+		m.state = StateDone
+		return m, nil
+
+	case tea.WindowSizeMsg:
+		m.refreshStatusTable()
+		return m, nil
+
+	case tea.KeyMsg:
+		if m.statusLoading {
+			return m, nil
+		}
+		switch msg.String() {
+		case "s":
+			m.statusSort = (m.statusSort + 1) % len(ghops.StatusSortKeys)
+			m.refreshStatusTable()
+			return m, nil
+		case "tab":
+			m.statusFilter = (m.statusFilter + 1) % len(statusFilters)
+			m.refreshStatusTable()
+			return m, nil
+		case "r":
+			return m.startStatus()
+		case "enter":
+			m.state = StateMenu
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	m.statusTable, cmd = m.statusTable.Update(msg)
+	return m, cmd
+}
+
+// refreshStatusTable sorts and filters the statuses into the table using
+// the current sort key and filter.
+func (m *TuiModel) refreshStatusTable() {
+	statuses := append([]ghops.RepoStatus(nil), m.statuses...)
+	ghops.SortStatuses(statuses, ghops.StatusSortKeys[m.statusSort])
+	statuses = statusFilters[m.statusFilter].filter.Apply(statuses)
+
+	now := time.Now()
+	rows := make([]table.Row, len(statuses))
+	widths := make([]int, len(ghops.StatusColumns))
+	for i, title := range ghops.StatusColumns {
+		widths[i] = lipgloss.Width(title)
+	}
+	for i, s := range statuses {
+		rows[i] = s.Row(now)
+		for j, cell := range rows[i] {
+			widths[j] = max(widths[j], lipgloss.Width(cell))
+		}
+	}
+	cols := make([]table.Column, len(widths))
+	for i, title := range ghops.StatusColumns {
+		cols[i] = table.Column{Title: title, Width: min(widths[i], 40)}
+	}
+
+	_, h := m.runViewSize()
+	m.statusTable = table.New(
+		table.WithColumns(cols),
+		table.WithRows(rows),
+		table.WithHeight(h-1),
+		table.WithFocused(true),
+	)
+}
+
+func (m TuiModel) renderStatus() string {
+	if m.statusLoading {
+		return fmt.Sprintf("Reading repository status... %s", m.sp.View())
+	}
+	header := TitleStyle.Render("Workspace status") + fmt.Sprintf(
+		" · %d of %d repos · sort: %s · show: %s",
+		len(m.statusTable.Rows()), len(m.statuses),
+		ghops.StatusSortKeys[m.statusSort], statusFilters[m.statusFilter].name)
+	footer := "↑/↓ scroll · s sort · tab filter · r refresh · enter back to menu · q/esc quit"
+	return header + "\n\n" + m.statusTable.View() + "\n" + footer
+}
@@ -1,40 +1,54 @@
 package ui
 
 import (
+	"context"
 	"fmt"
-	"math/rand"
 	"os/exec"
 	"strings"
-	"time"
 
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/progress"
 	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/huh"
 	zone "github.com/lrstanley/bubblezone"
 
+	"github.com/sanurb/ghpm/internal/config"
 	"github.com/sanurb/ghpm/internal/ghops"
+	"github.com/sanurb/ghpm/internal/git"
 	"github.com/sanurb/ghpm/internal/github"
+	"github.com/sanurb/ghpm/internal/remoteurl"
 )
 
-type clonedRepoMsg string
+// clonedRepoMsg reports the outcome of cloning a single repository.
+type clonedRepoMsg ghops.Result
 
-func cloneRepoCmd(repoName string) tea.Cmd {
-	d := time.Millisecond * time.Duration(rand.Intn(1000)+300)
-	return tea.Tick(d, func(t time.Time) tea.Msg {
-		return clonedRepoMsg(repoName)
-	})
+// cloneReposCmd clones repos in the background as described by opts,
+// sending one clonedRepoMsg per repo on ch as each clone finishes.
+func cloneReposCmd(ctx context.Context, repos []github.Repo, opts ghops.CloneOptions, ch chan<- clonedRepoMsg) tea.Cmd {
+	return func() tea.Msg {
+		go func() {
+			defer close(ch)
+			opts.OnResult = func(r ghops.Result) {
+				ch <- clonedRepoMsg(r)
+			}
+			ghops.CloneRepos(ctx, repos, opts)
+		}()
+		return nil
+	}
 }
 
-func multiCloneAllCmd(repos []string) tea.Cmd {
-	if len(repos) == 0 {
-		return func() tea.Msg {
-			return clonedRepoMsg("")
+// waitForCloneCmd waits for the next clone result on ch.
+func waitForCloneCmd(ch <-chan clonedRepoMsg) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-ch
+		if !ok {
+			return nil
 		}
+		return msg
 	}
-	return cloneRepoCmd(repos[0])
 }
 
 func max(a, b int) int {
@@ -51,6 +65,8 @@ func (m TuiModel) updateMenu(msg tea.Msg) (TuiModel, tea.Cmd) {
 		switch msg.String() {
 		case "?":
 			m.showHelp = !m.showHelp
+		case "d":
+			m.dryRun = !m.dryRun
 		case "up", "k":
 			if m.menuCursor > 0 {
 				m.menuCursor--
@@ -86,13 +102,14 @@ func (m TuiModel) handleMenuChoice(idx int) (TuiModel, tea.Cmd) {
 		m.state = StateRepoFetch
 		return m, tea.Batch(
 			m.sp.Tick,
-			fetchReposCmd("self", ""),
+			fetchReposCmd("self", "", m.repoLimit),
 		)
 
 	case "Clone Public Repos":
 		// This is the single blocking approach
 		// So no new state; we do it right here
 		usernamePtr := new(string)
+		*usernamePtr = config.AppConfig.DefaultUser
 		err := huh.NewInput().
 			Title("Enter GitHub Username").
 			Validate(func(v string) error {
@@ -113,7 +130,7 @@ func (m TuiModel) handleMenuChoice(idx int) (TuiModel, tea.Cmd) {
 		m.state = StateRepoFetch
 		return m, tea.Batch(
 			m.sp.Tick,
-			fetchReposCmd("public", *usernamePtr),
+			fetchReposCmd("public", *usernamePtr, m.repoLimit),
 		)
 
 	case "Clone Repos from an Org":
@@ -144,35 +161,63 @@ func (m TuiModel) handleMenuChoice(idx int) (TuiModel, tea.Cmd) {
 			return m, nil
 		}
 
-		// Fire off background
-		go ghops.RunCommandInAllRepos(".", *cmdPtr)
-		m.message = "Command executed in all repos."
-		m.state = StateDone
-		return m, nil
+		command := *cmdPtr
+		return m.startRunning(fmt.Sprintf("Running %q in all repos", command),
+			func(ctx context.Context, opts ghops.ExecOptions) ([]ghops.Result, error) {
+				return ghops.RunCommand(ctx, m.root, command, opts)
+			})
+
+	case "Convert Remotes":
+		to := remoteurl.SSH
+		err := huh.NewSelect[remoteurl.Protocol]().
+			Title("Switch the origin remote of every repo to").
+			Options(
+				huh.NewOption("SSH (git@github.com:owner/repo.git)", remoteurl.SSH),
+				huh.NewOption("HTTPS (https://github.com/owner/repo.git)", remoteurl.HTTPS),
+			).
+			Value(&to).
+			Run()
+		if err != nil {
+			m.message = "Remote conversion canceled"
+			m.state = StateDone
+			return m, nil
+		}
 
-	case "Set SSH Remote":
-		// Also do a blocking input
-		usrPtr := new(string)
-		err := huh.NewInput().
-			Title("Enter GitHub Username").
-			Validate(func(v string) error {
-				if strings.TrimSpace(v) == "" {
-					return fmt.Errorf("Username cannot be empty")
-				}
-				return nil
-			}).
-			Value(usrPtr).
+		return m.startRunning(fmt.Sprintf("Converting origin remotes to %s", to),
+			func(ctx context.Context, opts ghops.ExecOptions) ([]ghops.Result, error) {
+				return ghops.ConvertRemotes(ctx, m.root, ghops.ConvertOptions{To: to, Verify: true}, opts)
+			})
+
+	case "Push All Repos":
+		return m.startRunning("Pushing all repos",
+			func(ctx context.Context, opts ghops.ExecOptions) ([]ghops.Result, error) {
+				return ghops.Push(ctx, m.root, opts)
+			})
+
+	case "Pull All Repos":
+		mode := git.PullFFOnly
+		err := huh.NewSelect[git.PullMode]().
+			Title("How should upstream changes be integrated?").
+			Options(
+				huh.NewOption("Fast-forward only", git.PullFFOnly),
+				huh.NewOption("Rebase local commits", git.PullRebase),
+				huh.NewOption("Use git's pull settings", git.PullDefault),
+			).
+			Value(&mode).
 			Run()
 		if err != nil {
-			m.message = "User input canceled or invalid"
+			m.message = "Pull canceled"
 			m.state = StateDone
 			return m, nil
 		}
 
-		go ghops.SetSSHRemote(".", *usrPtr)
-		m.message = "SSH remote set for all repos."
-		m.state = StateDone
-		return m, nil
+		return m.startRunning("Pulling all repos",
+			func(ctx context.Context, opts ghops.ExecOptions) ([]ghops.Result, error) {
+				return ghops.Pull(ctx, m.root, mode, opts)
+			})
+
+	case "Workspace Status":
+		return m.startStatus()
 
 	case "Exit":
 		return m, tea.Quit
@@ -229,7 +274,7 @@ func (m TuiModel) updateOrgSelect(msg tea.Msg) (TuiModel, tea.Cmd) {
 			orgChoice := f.GetString("selectedOrg")
 			m.selectedOrg = orgChoice
 			m.state = StateRepoFetch
-			return m, fetchOrgReposCmd(orgChoice)
+			return m, fetchOrgReposCmd(orgChoice, m.repoLimit)
 		}
 	}
 	return m, cmd
@@ -244,13 +289,16 @@ func (m TuiModel) updateRepoFetch(msg tea.Msg) (TuiModel, tea.Cmd) {
 		return m, cmd
 
 	case reposMsg:
-		m.repos = msg
-		if len(m.repos) > 500 {
-			m.repos = m.repos[:500]
+		m.repos = msg.Repos
+		clear(m.selected)
+		m.updateSelectionStatus()
+		m.repoList.Title = "Repositories"
+		if ghops.Listing(msg).Truncated {
+			m.repoList.Title = fmt.Sprintf("Repositories (first %d only, limit reached)", msg.Limit)
 		}
 		items := make([]list.Item, 0, len(m.repos))
 		for _, r := range m.repos {
-			items = append(items, repoItem{name: r.Name, sshUrl: r.SSHUrl})
+			items = append(items, repoItem{repo: r})
 		}
 		m.repoList.SetItems(items)
 		m.repoList.Paginator.PerPage = m.pageSize
@@ -281,30 +329,111 @@ func (m TuiModel) updateRepoList(msg tea.Msg) (TuiModel, tea.Cmd) {
 		case "?":
 			m.showHelp = !m.showHelp
 			m.repoList.SetShowHelp(m.showHelp)
-		case "enter":
+		}
+		if m.repoList.FilterState() == list.Filtering {
+			break
+		}
+		switch {
+		case key.Matches(msg, m.keys.Toggle):
 			if sel, ok := m.repoList.SelectedItem().(repoItem); ok {
-				go ghops.CloneRepo(sel.sshUrl, sel.name)
-				m.message = fmt.Sprintf("Cloning %s...", sel.name)
-				m.state = StateDone
+				if k := sel.key(); m.selected[k] {
+					delete(m.selected, k)
+				} else {
+					m.selected[k] = true
+				}
+				m.updateSelectionStatus()
+			}
+		case key.Matches(msg, m.keys.ToggleVisible):
+			m.toggleVisible()
+		case key.Matches(msg, m.keys.Clone):
+			if len(m.selected) > 0 {
+				return m.startDownloading(m.selectedRepos())
 			}
+			if sel, ok := m.repoList.SelectedItem().(repoItem); ok {
+				return m.startDownloading([]github.Repo{sel.repo})
+			}
+		case key.Matches(msg, m.keys.CloneAll):
+			return m.startDownloading(m.repos)
+		}
+	}
+	return m, listCmd
+}
+
+// toggleVisible selects every repo matching the current filter, or
+// deselects them all if they are already selected.
+func (m *TuiModel) toggleVisible() {
+	visible := m.repoList.VisibleItems()
+	allSelected := true
+	for _, it := range visible {
+		if ri, ok := it.(repoItem); ok && !m.selected[ri.key()] {
+			allSelected = false
+			break
 		}
-		if key.Matches(msg, m.keys.CloneAll) {
-			m.downloadIndex = 0
-			m.downloadTarget = len(m.repos)
-			m.downloadRepos = make([]string, 0, m.downloadTarget)
-			for _, r := range m.repos {
-				m.downloadRepos = append(m.downloadRepos, r.Name)
+	}
+	for _, it := range visible {
+		if ri, ok := it.(repoItem); ok {
+			if allSelected {
+				delete(m.selected, ri.key())
+			} else {
+				m.selected[ri.key()] = true
 			}
-			m.downloading = true
-			m.done = false
-			m.state = StateDownloading
+		}
+	}
+	m.updateSelectionStatus()
+}
 
-			cmd := m.progress.SetPercent(0.0)
-			cloneCmd := multiCloneAllCmd(m.downloadRepos)
-			return m, tea.Batch(cmd, cloneCmd)
+// selectedRepos returns the selected repos in listing order.
+func (m TuiModel) selectedRepos() []github.Repo {
+	var repos []github.Repo
+	for _, r := range m.repos {
+		if m.selected[repoItem{repo: r}.key()] {
+			repos = append(repos, r)
 		}
 	}
-	return m, listCmd
+	return repos
+}
+
+// updateSelectionStatus shows the number of selected repos in the list's
+// status bar, next to the item count.
+func (m *TuiModel) updateSelectionStatus() {
+	var suffix string
+	if n := len(m.selected); n > 0 {
+		suffix = fmt.Sprintf(" · %d selected", n)
+	}
+	m.repoList.SetStatusBarItemName("repo"+suffix, "repos"+suffix)
+}
+
+// startDownloading switches to the downloading state and starts cloning
+// repos, m.jobs at a time.
+func (m TuiModel) startDownloading(repos []github.Repo) (TuiModel, tea.Cmd) {
+	layout, err := ghops.ParseLayout(m.layout)
+	if err != nil {
+		m.message = err.Error()
+		m.state = StateDone
+		return m, nil
+	}
+
+	m.downloadIndex = 0
+	m.downloadTarget = len(repos)
+	m.downloadRepos = repos
+	m.downloadResults = nil
+	m.lastCloned = ""
+	m.downloading = true
+	m.done = false
+	m.state = StateDownloading
+
+	if len(repos) == 0 {
+		m.downloading = false
+		m.done = true
+		return m, nil
+	}
+
+	ch := make(chan clonedRepoMsg)
+	m.cloneResults = ch
+	ctx := m.runContext()
+	cmd := m.progress.SetPercent(0.0)
+	opts := ghops.CloneOptions{Dest: m.root, Layout: layout, Jobs: m.jobs, OnConflict: m.onConflict, Hooks: m.hooks}
+	return m, tea.Batch(cmd, cloneReposCmd(ctx, repos, opts, ch), waitForCloneCmd(ch))
 }
 
 // =============== DOWNLOADING ===============
@@ -315,18 +444,27 @@ func (m TuiModel) updateDownloading(msg tea.Msg) (TuiModel, tea.Cmd) {
 		m.sp = newSpin
 		return m, cmd
 
-	case clonedRepoMsg:
-		if m.downloadIndex >= m.downloadTarget-1 {
-			m.done = true
-			m.downloading = false
-			return m, tea.Quit
+	case tea.KeyMsg:
+		if m.done {
+			m.state = StateMenu
+			m.done = false
+			m.downloadRepos = nil
+			m.downloadResults = nil
 		}
+		return m, nil
+
+	case clonedRepoMsg:
+		m.downloadResults = append(m.downloadResults, ghops.Result(msg))
 		m.downloadIndex++
+		m.lastCloned = msg.Name
 		percent := float64(m.downloadIndex) / float64(m.downloadTarget)
 		progressCmd := m.progress.SetPercent(percent)
-		nextRepo := m.downloadRepos[m.downloadIndex]
-		cloneCmd := cloneRepoCmd(nextRepo)
-		return m, tea.Batch(progressCmd, cloneCmd)
+		if m.downloadIndex >= m.downloadTarget {
+			m.done = true
+			m.downloading = false
+			return m, progressCmd
+		}
+		return m, tea.Batch(progressCmd, waitForCloneCmd(m.cloneResults))
 
 	case progress.FrameMsg:
 		newProg, cmd := m.progress.Update(msg)
@@ -338,6 +476,132 @@ func (m TuiModel) updateDownloading(msg tea.Msg) (TuiModel, tea.Cmd) {
 	return m, nil
 }
 
+// =============== RUNNING ===============
+
+// repoResultMsg carries the result of a batch operation in one repo.
+type repoResultMsg ghops.Result
+
+// runFinishedMsg is sent once a batch operation has finished in every repo.
+type runFinishedMsg struct {
+	results []ghops.Result
+	err     error
+}
+
+// batchRunFunc runs a batch operation across local repos.
+type batchRunFunc func(ctx context.Context, opts ghops.ExecOptions) ([]ghops.Result, error)
+
+// startRunning switches to the running state and starts run in the
+// background, streaming its per-repo results into the output viewport.
+func (m TuiModel) startRunning(title string, run batchRunFunc) (TuiModel, tea.Cmd) {
+	ch := make(chan tea.Msg)
+	m.runTitle = title
+	m.runLog = ""
+	m.runCount = 0
+	m.runResults = nil
+	m.runErr = nil
+	m.runDone = false
+	m.runEvents = ch
+	m.runView = viewport.New(m.runViewSize())
+	m.state = StateRunning
+	ctx := m.runContext()
+
+	start := func() tea.Msg {
+		go func() {
+			defer close(ch)
+			results, err := run(ctx, ghops.ExecOptions{
+				Jobs: m.jobs,
+				OnResult: func(r ghops.Result) {
+					ch <- repoResultMsg(r)
+				},
+			})
+			ch <- runFinishedMsg{results: results, err: err}
+		}()
+		return nil
+	}
+	return m, tea.Batch(m.sp.Tick, start, waitForRunEventCmd(ch))
+}
+
+// runContext returns the context a batch operation runs in. In dry-run
+// mode it carries a fresh plan, kept in m.plan, that mutating operations
+// record their commands in.
+func (m *TuiModel) runContext() context.Context {
+	m.plan = nil
+	if !m.dryRun {
+		return context.Background()
+	}
+	m.plan = &git.Plan{}
+	return git.WithPlan(context.Background(), m.plan)
+}
+
+// waitForRunEventCmd waits for the next message from a running batch.
+func waitForRunEventCmd(ch <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		return <-ch // nil once the channel is closed
+	}
+}
+
+// runViewSize returns the viewport size left over after the header and footer.
+func (m TuiModel) runViewSize() (int, int) {
+	w, h := m.width, m.height-4
+	if w <= 0 {
+		w = 80
+	}
+	if h <= 0 {
+		h = 20
+	}
+	return w, h
+}
+
+func (m TuiModel) updateRunning(msg tea.Msg) (TuiModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.runView.Width, m.runView.Height = m.runViewSize()
+		return m, nil
+
+	case spinner.TickMsg:
+		if m.runDone {
+			return m, nil
+		}
+		newSpin, cmd := m.sp.Update(msg)
+		m.sp = newSpin
+		return m, cmd
+
+	case repoResultMsg:
+		m.runCount++
+		m.appendRunLog(renderRepoResult(ghops.Result(msg)))
+		return m, waitForRunEventCmd(m.runEvents)
+
+	case runFinishedMsg:
+		m.runDone = true
+		m.runResults = msg.results
+		m.runErr = msg.err
+		m.appendRunLog(m.renderRunSummary())
+		m.appendRunLog(renderPlan(m.plan))
+		return m, nil
+
+	case tea.KeyMsg:
+		if m.runDone && msg.String() == "enter" {
+			m.state = StateMenu
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	m.runView, cmd = m.runView.Update(msg)
+	return m, cmd
+}
+
+// appendRunLog adds text to the output viewport, following the tail of the
+// log unless the user has scrolled up.
+func (m *TuiModel) appendRunLog(text string) {
+	follow := m.runView.AtBottom()
+	m.runLog += text
+	m.runView.SetContent(m.runLog)
+	if follow {
+		m.runView.GotoBottom()
+	}
+}
+
 // =============== DONE ===============
 func (m TuiModel) updateDone(msg tea.Msg) (TuiModel, tea.Cmd) {
 	if _, ok := msg.(tea.KeyMsg); ok {
@@ -352,42 +616,49 @@ func fetchOrgsCmd() tea.Cmd {
 	return func() tea.Msg {
 		orgs, err := ghops.ListUserOrgs()
 		if err != nil {
-			out, _ := exec.Command("gh", "auth", "status").CombinedOutput()
-			authMsg := strings.TrimSpace(string(out))
-			return errMsg{fmt.Errorf("%w\nEnsure you're authenticated.\n%s", err, authMsg)}
+			return errMsg{withAuthHint(err)}
 		}
 		return orgsMsg(orgs)
 	}
 }
 
-func fetchOrgReposCmd(orgLogin string) tea.Cmd {
+func fetchOrgReposCmd(orgLogin string, limit int) tea.Cmd {
 	return func() tea.Msg {
-		repos, err := ghops.ListOrgRepos(orgLogin)
+		listing, err := ghops.ListOrgRepos(orgLogin, limit)
 		if err != nil {
-			out, _ := exec.Command("gh", "auth", "status").CombinedOutput()
-			authMsg := strings.TrimSpace(string(out))
-			return errMsg{fmt.Errorf("%w\nEnsure you're authenticated.\n%s", err, authMsg)}
+			return errMsg{withAuthHint(err)}
 		}
-		return reposMsg(repos)
+		return reposMsg(listing)
 	}
 }
 
-func fetchReposCmd(mode, username string) tea.Cmd {
+func fetchReposCmd(mode, username string, limit int) tea.Cmd {
 	return func() tea.Msg {
 		var (
-			repos []github.Repo
-			err   error
+			listing ghops.Listing
+			err     error
 		)
 		if mode == "self" {
-			repos, err = ghops.ListSelfRepos()
+			listing, err = ghops.ListSelfRepos(limit)
 		} else {
-			repos, err = ghops.ListPublicRepos(username)
+			listing, err = ghops.ListPublicRepos(username, limit)
 		}
 		if err != nil {
+			return errMsg{withAuthHint(err)}
+		}
+		return reposMsg(listing)
+	}
+}
+
+// withAuthHint appends an authentication reminder to err and, when the gh
+// backend is in use, the output of "gh auth status".
+func withAuthHint(err error) error {
+	if c, cerr := ghops.Client(); cerr == nil {
+		if _, ok := c.(*github.GHClient); ok {
 			out, _ := exec.Command("gh", "auth", "status").CombinedOutput()
 			authMsg := strings.TrimSpace(string(out))
-			return errMsg{fmt.Errorf("%w\nEnsure you're authenticated.\n%s", err, authMsg)}
+			return fmt.Errorf("%w\nEnsure you're authenticated.\n%s", err, authMsg)
 		}
-		return reposMsg(repos)
 	}
+	return fmt.Errorf("%w\nEnsure you're authenticated (github_token or GITHUB_TOKEN).", err)
 }